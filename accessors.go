@@ -0,0 +1,57 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// lookupGetter calls the getter method of source for the field with the given
+// name, and returns its result. Methods with a pointer receiver are found as
+// well if source is addressable.
+func lookupGetter(source reflect.Value, name string) (value reflect.Value, path string, found bool) {
+	if source.CanAddr() {
+		source = source.Addr()
+	}
+	for _, methodName := range []string{"Get" + name, name} {
+		method := source.MethodByName(methodName)
+		if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+			continue
+		}
+		return method.Call(nil)[0], methodName + "()", true
+	}
+	return reflect.Value{}, "", false
+}
+
+// lookupSetter returns the setter method of destVal for the field with the
+// given name. Setters take a single argument and return nothing.
+func lookupSetter(destVal reflect.Value, name string) (reflect.Value, bool) {
+	if destVal.CanAddr() {
+		destVal = destVal.Addr()
+	}
+	method := destVal.MethodByName("Set" + name)
+	if !method.IsValid() || method.Type().NumIn() != 1 || method.Type().NumOut() != 0 {
+		return reflect.Value{}, false
+	}
+	return method, true
+}
+
+// mapToSetter maps the source field with the given name to the argument type
+// of setter, and calls setter with the result.
+func mapToSetter(source, setter reflect.Value, sourceFieldName string, tag fieldTag, opts mapOptions) {
+	sourceField, sourcePath, found := lookupField(source, sourceFieldName)
+	if !found && opts.getters {
+		sourceField, sourcePath, found = lookupGetter(source, sourceFieldName)
+	}
+	if !found {
+		panic(fmt.Sprintf("no source field '%s' for setter", sourceFieldName))
+	}
+	if !sourceField.IsValid() || tag.omitEmpty && opts.isZero(sourceField) {
+		return
+	}
+	opts.sourcePath = joinPath(opts.sourcePath, sourcePath)
+	arg := reflect.New(setter.Type().In(0)).Elem()
+	mapValues(sourceField, arg, opts)
+	setter.Call([]reflect.Value{arg})
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type encapsulated struct {
+	name  string
+	count int
+}
+
+func (e *encapsulated) GetName() string { return e.name }
+
+func (e encapsulated) Count() int { return e.count }
+
+func (e encapsulated) Label(prefix string) string { return prefix + e.name }
+
+func (e encapsulated) Pair() (string, int) { return e.name, e.count }
+
+func TestWithGetters(t *testing.T) {
+	source := encapsulated{"foo", 42}
+	dest := struct {
+		Name  string
+		Count int64
+	}{}
+
+	MapToDestination(&source, &dest, WithGetters())
+	assert.Equal(t, "foo", dest.Name)
+	assert.Equal(t, int64(42), dest.Count)
+}
+
+func TestWithGettersIgnoresMethodsWithArgumentsOrMultipleResults(t *testing.T) {
+	source := encapsulated{"foo", 42}
+	for _, dest := range []interface{}{
+		&struct{ Label string }{},
+		&struct{ Pair string }{},
+	} {
+		func() {
+			defer func() { recover() }()
+			MapToDestination(&source, dest, WithGetters())
+			t.Errorf("Should have panicked mapping to %T", dest)
+		}()
+	}
+}
+
+func TestGettersAreOptIn(t *testing.T) {
+	defer func() { recover() }()
+	source := encapsulated{"foo", 42}
+	dest := struct{ Name string }{}
+
+	MapToDestination(&source, &dest)
+	t.Error("Should have panicked")
+}
+
+type guarded struct {
+	name  string
+	count int
+}
+
+func (g *guarded) SetName(name string) { g.name = name }
+
+func (g *guarded) SetCount(count int64) {
+	if count < 0 {
+		count = 0
+	}
+	g.count = int(count)
+}
+
+func TestWithSetters(t *testing.T) {
+	source := struct {
+		Name  string
+		Count int
+	}{"foo", -1}
+	dest := guarded{count: 42}
+
+	MapFromSource(&source, &dest, WithSetters())
+	assert.Equal(t, "foo", dest.name)
+	assert.Equal(t, 0, dest.count)
+}
+
+func TestWithSettersFailsOnIncompatibleArgument(t *testing.T) {
+	defer func() { recover() }()
+	source := struct{ Name []int }{}
+	dest := guarded{}
+
+	MapFromSource(&source, &dest, WithSetters())
+	t.Error("Should have panicked")
+}
+
+func TestWithSettersPrefersExportedFields(t *testing.T) {
+	source := struct{ Foo int }{42}
+	dest := settableFoo{}
+
+	MapFromSource(&source, &dest, WithSetters())
+	assert.Equal(t, 42, dest.Foo)
+	assert.False(t, dest.setterCalled)
+}
+
+type settableFoo struct {
+	Foo          int
+	setterCalled bool
+}
+
+func (s *settableFoo) SetFoo(foo int) { s.setterCalled = true }
@@ -11,87 +11,499 @@
 package automapper
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math"
 	"reflect"
+	"strconv"
+	"strings"
 )
 
-type mapOptions struct {
-	useSourceMemberList bool
-}
-
 // MapToDestination fills out the fields in dest with values from source. All fields in the
 // destination object must exist in the source object.
-func MapToDestination(source, dest interface{}) {
-	var destType = reflect.TypeOf(dest)
-	if destType.Kind() != reflect.Ptr {
-		panic("Dest must be a pointer type")
-	}
-	var sourceVal = reflect.ValueOf(source)
-	var destVal = reflect.ValueOf(dest).Elem()
-	mapValues(sourceVal, destVal, mapOptions{useSourceMemberList: false})
+//
+// Source fields are found by name following Go's rules for promoted fields, so
+// a field of the source struct itself wins over a field of the same name in
+// an embedded struct. Fields are promoted through any number of embedding
+// levels, including embedded pointers; a field promoted through a nil pointer
+// leaves the destination unchanged. An automapper tag with a dotted path, e.g.
+// `automapper:"Embedded.Foo"`, selects a nested field explicitly. A type name
+// followed by a slash selects the field of that type instead, e.g.
+// `automapper:"BillingAddress/Street"` selects Street of the field of type
+// BillingAddress, which is useful when several fields share a shape. When a field
+// is not found this way, the struct fields of the source are searched, and it
+// is an error if more than one of them has a field of that name.
+//
+// A tag may list several candidate source fields separated by "|", e.g.
+// `automapper:"FullName|DisplayName|Name"`. The first candidate with a
+// non-empty value is mapped. When all of them are empty, the first candidate
+// that exists is mapped, so an empty value still overwrites the destination.
+//
+// An embedded struct tagged with a field name, e.g. `automapper:"Billing"`, is
+// mapped as a whole from that source field instead of having its fields
+// promoted. Tagged with `automapper:"-"`, it is skipped entirely. When dest
+// embeds the type of source itself, or a pointer to it, source is mapped into
+// that embedded field. The other fields of dest are mapped as usual, except
+// that fields without a source field are left unchanged.
+//
+// When dest points to a map[string]interface{} and source is a struct, the map
+// is filled with the fields of source like MapToMap does.
+//
+// Strings map to runes and bytes only if they hold exactly one character, or
+// one byte respectively, and the empty string maps to zero. Runes map to their
+// UTF-8 encoding, and a byte maps to the one byte string holding it.
+func MapToDestination(source, dest interface{}, opts ...Option) {
+	mapTopLevel(source, dest, newMapOptions(false, opts))
+}
+
+// MapToDestinationStrictTypes works like MapToDestination with the
+// WithStrictTypes option, so values are never converted in ways that may lose
+// information.
+func MapToDestinationStrictTypes(source, dest interface{}, opts ...Option) {
+	MapToDestination(source, dest, append(opts, WithStrictTypes())...)
 }
 
 // MapFromSource fills out the fields in dest with values from source. All fields in the
 // source object must exist in the destination object.
-func MapFromSource(source, dest interface{}) {
-	var destType = reflect.TypeOf(dest)
-	if destType.Kind() != reflect.Ptr {
-		panic("Dest must be a pointer type")
-	}
-	var sourceVal = reflect.ValueOf(source)
-	var destVal = reflect.ValueOf(dest).Elem()
-	mapValues(sourceVal, destVal, mapOptions{useSourceMemberList: true})
+//
+// The automapper tag of a source field names the destination field it maps
+// to. Like source fields for MapToDestination, a plain name is resolved by
+// Go's rules for promoted fields, while a dotted path or a type name followed
+// by a slash selects a nested field explicitly. Nil pointers on such a path
+// are allocated.
+func MapFromSource(source, dest interface{}, opts ...Option) {
+	mapTopLevel(source, dest, newMapOptions(true, opts))
 }
 
 // MapFromSourceMap fills out the fields in dest with values from source map. All fields in the
-// source map must exist in the destination object.
-func MapFromSourceMap(source map[string]interface{}, dest interface{}) {
+// source map must exist in the destination object. Nested maps, including
+// the elements of slices, are mapped into structs the same way, so decoded
+// JSON objects can be mapped in one call.
+func MapFromSourceMap(source map[string]interface{}, dest interface{}, opts ...Option) {
+	mapRootValues(reflect.ValueOf(source), destinationValue(dest), newMapOptions(true, opts))
+}
+
+// MapFromSourceMapJSON works like MapFromSourceMap, but matches the keys of
+// source against the json tags of the destination fields, like encoding/json
+// does. So a map produced by json.Unmarshal, holding e.g. the key
+// "user_name", fills the field tagged `json:"user_name"`. Fields without a
+// json tag are matched by their name, and fields tagged `json:"-"` are never
+// matched. Nested maps are matched against the json tags of nested structs.
+func MapFromSourceMapJSON(source map[string]interface{}, dest interface{}, opts ...Option) {
+	var options = newMapOptions(true, opts)
+	options.jsonKeys = true
+	mapRootValues(reflect.ValueOf(source), destinationValue(dest), options)
+}
+
+// MapInto works like MapToDestination, but merges source into the values
+// already present in dest instead of replacing them where possible:
+//
+//   - Structs are always mapped field by field, so fields that are not mapped
+//     keep their values. This is also the behavior of MapToDestination.
+//   - Non-nil pointers are kept, and the value they point to is mapped from
+//     the source. Nil pointers are allocated as usual.
+//   - Slices keep their existing elements, which are mapped from the elements
+//     of the source slice at the same index. The slice is truncated or grown
+//     to the length of the source slice.
+//
+// Values of any other kind, as well as values of the exact same type as the
+// source value, are replaced. This includes structs and slices, but not
+// pointers: a non-nil pointer of the same type is kept as well, and only the
+// value it points to is replaced.
+func MapInto(source, dest interface{}, opts ...Option) {
+	var options = newMapOptions(false, opts)
+	options.preserveSliceElements = true
+	options.reusePointers = true
+	mapTopLevel(source, dest, options)
+}
+
+// MapSliceInto maps the elements of the slice or array source, and appends the
+// results to the slice that dest points to. The elements already in the
+// destination slice are left untouched. It panics with an
+// *IncompatibleTypesError if source is not a slice or an array, or a pointer
+// to one.
+func MapSliceInto(source, dest interface{}, opts ...Option) {
+	var destVal = destinationValue(dest)
+	if destVal.Kind() != reflect.Slice {
+		panic("Dest must be a pointer to a slice")
+	}
+	if sourceType := reflect.TypeOf(source); sourceType == nil || !isSliceOrArray(derefType(sourceType)) {
+		panic(&IncompatibleTypesError{SourceType: sourceType, DestType: destVal.Type(), Err: errors.New("source must be a slice or an array")})
+	}
+	var appended = reflect.New(destVal.Type()).Elem()
+	mapTopLevel(source, appended.Addr().Interface(), newMapOptions(false, opts))
+	destVal.Set(reflect.AppendSlice(destVal, appended))
+}
+
+// MapWithMask works like MapInto, but only maps the destination fields
+// selected by paths, leaving all other fields untouched. A path is the dotted
+// path of a destination field, e.g. "Profile.Name", which includes the names
+// of embedded structs. Selecting a struct field selects all of its fields.
+func MapWithMask(source, dest interface{}, paths []string, opts ...Option) {
+	var options = newMapOptions(false, opts)
+	options.preserveSliceElements = true
+	options.reusePointers = true
+	options.mask = newFieldMask(paths)
+	mapTopLevel(source, dest, options)
+}
+
+// newFieldMask returns a field mask selecting paths. The parents of the paths
+// are included as well, but they are not selected themselves.
+func newFieldMask(paths []string) map[string]bool {
+	mask := map[string]bool{}
+	for _, path := range paths {
+		mask[path] = true
+		for i := strings.LastIndex(path, "."); i >= 0; i = strings.LastIndex(path, ".") {
+			path = path[:i]
+			if _, ok := mask[path]; !ok {
+				mask[path] = false
+			}
+		}
+	}
+	return mask
+}
+
+// MapWithAliases works like MapToDestination, but looks up the source fields
+// of the destination fields listed in aliases under the name given there.
+// Aliases are keyed by the dotted path of the destination field, e.g.
+// "Address.Street", and may name a dotted source path as well. Other fields
+// are matched by name as usual.
+func MapWithAliases(source, dest interface{}, aliases map[string]string, opts ...Option) {
+	var options = newMapOptions(false, opts)
+	options.aliases = aliases
+	mapTopLevel(source, dest, options)
+}
+
+// MapValues works like MapToDestination, but maps between values that have
+// been obtained through reflection already. dest must be settable, e.g. the
+// element of a pointer. Unlike MapToDestination, MapValues returns mapping
+// errors rather than panicking.
+func MapValues(source, dest reflect.Value, opts ...Option) (err error) {
+	defer recoverError(&err)
+	if !source.IsValid() || !dest.IsValid() {
+		return errors.New("source and dest must be valid values")
+	}
+	if !dest.CanSet() {
+		return fmt.Errorf("dest of type %v must be settable", dest.Type())
+	}
+	mapRootValues(source, dest, newMapOptions(false, opts))
+	return nil
+}
+
+// SafeMap runs fn and returns any panic raised by it as an error. It is meant
+// to wrap calls to the panicking mapping functions in one place:
+//
+//	err := SafeMap(func() { MapToDestination(source, &dest) })
+//
+// The error message contains the field and type information of the panic.
+func SafeMap(fn func()) (err error) {
+	defer recoverError(&err)
+	fn()
+	return nil
+}
+
+func mapTopLevel(source, dest interface{}, opts mapOptions) {
+	destVal := destinationValue(dest)
+	if sourceVal, ok := derefValue(reflect.ValueOf(source)); ok && sourceVal.Kind() == reflect.Struct && isInterfaceMap(destVal.Type()) {
+		// A map destination is filled like MapToMap does.
+		mapStructToMap(sourceVal, destVal, opts)
+		return
+	}
+	mapRootValues(reflect.ValueOf(source), destVal, opts)
+}
+
+// mapRootValues maps the values at the root of a mapping, setting up the state
+// shared by all of its recursive calls.
+func mapRootValues(sourceVal, destVal reflect.Value, opts mapOptions) {
+	opts.state = newMapState(opts)
+	if opts.unsafeUnexported {
+		sourceVal = addressable(sourceVal)
+	}
+	mapValues(sourceVal, destVal, opts)
+	if opts.verifiesFields() {
+		verifyAllFieldsMapped(sourceVal.Type(), destVal.Type(), opts)
+	}
+	verifyRequiredFields(destVal, opts)
+}
+
+// destinationValue returns the value that dest points to. It panics if dest is
+// not a pointer, or if it is a nil pointer, as there would be nowhere to
+// store the mapped values. When dest points to another pointer, all pointer
+// levels are followed, allocating nil pointers as needed.
+func destinationValue(dest interface{}) reflect.Value {
 	var destType = reflect.TypeOf(dest)
-	if destType.Kind() != reflect.Ptr {
+	if destType == nil || destType.Kind() != reflect.Ptr {
 		panic("Dest must be a pointer type")
 	}
-
-	var destVal = reflect.ValueOf(dest).Elem()
-	for key, value := range source {
-		destFieldVal := destVal.FieldByName(key)
-		mapValues(reflect.ValueOf(value), destFieldVal, mapOptions{useSourceMemberList: true})
+	var destVal = reflect.ValueOf(dest)
+	if destVal.IsNil() {
+		panic(fmt.Sprintf("Dest must not be a nil pointer. Got a nil %v, pass the address of an allocated value instead", destType))
+	}
+	destVal = destVal.Elem()
+	for destVal.Kind() == reflect.Ptr {
+		if destVal.IsNil() {
+			destVal.Set(reflect.New(destVal.Type().Elem()))
+		}
+		destVal = destVal.Elem()
 	}
+	return destVal
 }
 
 func mapValues(sourceVal, destVal reflect.Value, opts mapOptions) {
 	sourceType := sourceVal.Type()
 	destType := destVal.Type()
-	if destType.Kind() == reflect.Struct && sourceVal.Type().Kind() == reflect.Ptr {
+	opts.depth++
+	opts.embedsSource = false
+	opts.verifyDepth(opts.depth)
+	opts.state.visit(opts.sourcePath, opts.destPath)
+	// Converters are consulted before anything else, so they can replace any
+	// other way of mapping two types, e.g. to collapse a struct into a scalar.
+	if conv, ok := opts.converter(sourceType, destType); ok {
+		if opts.logger != nil {
+			opts.logger(opts.destPath, fmt.Sprintf("converting %v to %v with a converter", sourceType, destType))
+		}
+		convertLeaf(sourceVal, destVal, opts, conv)
+	} else if opts.jsonDecode && isByteSlice(sourceType) && derefType(destType).Kind() == reflect.Struct {
+		decodeJSON(sourceVal, destVal)
+	} else if opts.encoder != 0 && isEncodingPair(sourceType, destType) {
+		mapEncoded(sourceVal, destVal, opts.encoder)
+	} else if opts.sqlValueTypes && isScannerPair(sourceType, destType) {
+		mapScanned(sourceVal, destVal)
+	} else if opts.sqlValueTypes && isValuerPair(sourceType, destType) {
+		mapValued(sourceVal, destVal, opts)
+	} else if sourceType.Kind() == reflect.Ptr && destType.Kind() != reflect.Ptr && destType.Kind() != reflect.Interface {
+		// A nil source maps as the zero value, which still verifies that
+		// the types are compatible.
 		if sourceVal.IsNil() {
 			sourceVal = reflect.New(sourceType.Elem())
 		}
 		sourceVal = sourceVal.Elem()
 		mapValues(sourceVal, destVal, opts)
-	} else if destType == sourceType {
-		destVal.Set(sourceVal)
+		return
+	} else if (opts.reusePointers || opts.reuseNestedPointers) && destType.Kind() == reflect.Ptr && !destVal.IsNil() && !valueIsNil(sourceVal) {
+		mapValues(sourceVal, destVal.Elem(), opts)
+	} else if destType.Kind() == reflect.Slice && sourceType.Kind() == reflect.Slice && sourceVal.IsNil() {
+		mapNilSlice(sourceVal, destVal, opts)
+	} else if destType == sourceType && !(destType.Kind() == reflect.Slice && opts.sliceFilter != nil) && opts.mask == nil &&
+		!opts.transformsFieldsIn(opts.destPath) {
+		// Pointers are always cloned, including those held by structs,
+		// slices and maps, so the destination never aliases the values the
+		// source points to.
+		if opts.deepCopy || containsPointers(destType) {
+			destVal.Set(deepCopy(sourceVal, opts))
+		} else {
+			destVal.Set(sourceVal)
+		}
+		opts.state.complete(opts.sourcePath, opts.destPath)
+	} else if destType.Kind() == reflect.Interface && sourceType.Kind() == reflect.Interface && sourceType.AssignableTo(destType) {
+		// Interface values are assigned as they are, keeping their dynamic
+		// type, rather than being mapped.
+		if opts.deepCopy {
+			destVal.Set(deepCopy(sourceVal, opts))
+		} else {
+			destVal.Set(sourceVal)
+		}
+	} else if sourceType.Kind() == reflect.Interface {
+		// A nil interface holds no value to map, so the destination is reset
+		// to its zero value, e.g. for optional fields of decoded data.
+		if sourceVal.IsNil() {
+			if opts.logger != nil {
+				opts.logger(opts.destPath, "source is a nil interface, setting destination to its zero value")
+			}
+			destVal.Set(reflect.Zero(destType))
+			return
+		}
+		mapValues(sourceVal.Elem(), destVal, opts)
+		return
+	} else if isStringBytesPair(sourceType, destType) {
+		// A string/[]byte conversion always copies, so the destination
+		// never aliases the source bytes.
+		destVal.Set(sourceVal.Convert(destType))
+	} else if isByteArrayPair(sourceType, destType) {
+		mapBytes(sourceVal, destVal, opts)
+	} else if destType.Kind() == reflect.Struct && isStringMap(sourceType) {
+		mapStringMapToStruct(sourceVal, destVal, opts)
 	} else if destType.Kind() == reflect.Struct && sourceType.Kind() == reflect.Struct {
 		mapFields(sourceVal, destVal, opts)
+	} else if sourceType.Kind() == reflect.Struct && isInterfaceMap(destType) {
+		destVal.Set(reflect.MakeMap(destType))
+		mapStructToMap(sourceVal, destVal, opts)
+	} else if opts.wrapSingleElements && destType.Kind() == reflect.Slice && sourceType.Kind() == reflect.Struct {
+		wrapped := reflect.MakeSlice(reflect.SliceOf(sourceType), 1, 1)
+		wrapped.Index(0).Set(sourceVal)
+		mapSlice(wrapped, destVal, opts)
+	} else if opts.wrapSingleElements && destType.Kind() == reflect.Struct && sourceType.Kind() == reflect.Slice {
+		unwrapFirstElement(sourceVal, destVal, opts)
+		return
+	} else if opts.wrapScalars && destType.Kind() == reflect.Struct && isScalarKind(sourceType.Kind()) {
+		wrapScalar(sourceVal, destVal, opts)
 	} else if destType.Kind() == reflect.Ptr {
 		if valueIsNil(sourceVal) {
+			if opts.logger != nil {
+				opts.logger(opts.destPath, "source is nil, leaving destination pointer nil")
+			}
+			if !opts.reusePointers {
+				destVal.Set(reflect.Zero(destType))
+			}
+			// There are no fields below a nil pointer to verify.
+			opts.state.complete(opts.sourcePath, opts.destPath)
+			return
+		}
+		if shared, ok := opts.state.sharedPointer(sourceVal, destType); ok {
+			destVal.Set(shared)
 			return
 		}
 		val := reflect.New(destType.Elem())
+		opts.state.sharePointer(sourceVal, val)
 		mapValues(sourceVal, val.Elem(), opts)
 		destVal.Set(val)
+	} else if destType.Kind() == reflect.Slice && sourceType.Kind() == reflect.Map {
+		mapMapToSlice(sourceVal, destVal, opts)
 	} else if destType.Kind() == reflect.Slice {
 		mapSlice(sourceVal, destVal, opts)
+	} else if destType.Kind() == reflect.Array && (sourceType.Kind() == reflect.Slice || sourceType.Kind() == reflect.Array) {
+		mapArray(sourceVal, destVal, opts)
+	} else if destType.Kind() == reflect.Map && sourceType.Kind() == reflect.Map {
+		mapMap(sourceVal, destVal, opts)
 	} else {
-		destVal.Set(sourceVal.Convert(destType))
+		// Mismatched types are checked up front, so they abort the mapping
+		// even when conversion errors are tolerated.
+		opts.verifyConvertible(sourceType, destType)
+		if isCharacterPair(sourceType, destType) {
+			convertLeaf(sourceVal, destVal, opts, func(_ context.Context, sourceVal reflect.Value) reflect.Value {
+				return convertCharacter(sourceVal, destType)
+			})
+			return
+		}
+		convertLeaf(sourceVal, destVal, opts, func(_ context.Context, sourceVal reflect.Value) reflect.Value {
+			if opts.overflowCheck && overflows(sourceVal, destType) {
+				panic(fmt.Sprintf("value %v overflows %v", sourceVal, destType))
+			}
+			return sourceVal.Convert(destType)
+		})
+	}
+	// Structs are validated once they are mapped, however they were mapped,
+	// so this includes structs that are copied or converted as a whole.
+	if destType.Kind() == reflect.Struct {
+		validate(destVal, opts)
+	}
+}
+
+// transformsFieldsIn returns true if a field transform applies to a field
+// inside the value at path, so the value must be mapped field by field rather
+// than copied as a whole.
+func (o mapOptions) transformsFieldsIn(path string) bool {
+	prefix := withoutIndexes(path) + "."
+	for transformed := range o.fieldTransforms {
+		if path == "" || strings.HasPrefix(transformed, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyDepth panics if a value nested depth levels deep exceeds the maximum
+// depth of the mapping, if any.
+func (o mapOptions) verifyDepth(depth int) {
+	if o.maxDepth > 0 && depth > o.maxDepth {
+		panic(fmt.Sprintf("maximum mapping depth of %d exceeded at '%s'", o.maxDepth, o.destPath))
+	}
+}
+
+// verifyConvertible panics if values of sourceType can't be converted to
+// destType, or only by losing information when types are strict.
+func (o mapOptions) verifyConvertible(sourceType, destType reflect.Type) {
+	if o.strictTypes && !isLosslessConversion(sourceType, destType) {
+		panic(fmt.Sprintf("cannot convert %v to %v with strict types; register a converter to allow it", sourceType, destType))
+	}
+	// Convert panics for these as well, but without suggesting a remedy.
+	if !isCharacterPair(sourceType, destType) && !sourceType.ConvertibleTo(destType) {
+		panic(fmt.Sprintf("cannot convert %v to %v; register a converter to map them", sourceType, destType))
 	}
 }
 
+// overflows returns true if the numeric value in sourceVal cannot be
+// represented by destType. Non-numeric types never overflow.
+func overflows(sourceVal reflect.Value, destType reflect.Type) bool {
+	dest := reflect.New(destType).Elem()
+	switch {
+	case isIntKind(destType.Kind()):
+		switch {
+		case isIntKind(sourceVal.Kind()):
+			return dest.OverflowInt(sourceVal.Int())
+		case isUintKind(sourceVal.Kind()):
+			return sourceVal.Uint() > math.MaxInt64 || dest.OverflowInt(int64(sourceVal.Uint()))
+		case isFloatKind(sourceVal.Kind()):
+			f := sourceVal.Float()
+			return f < math.MinInt64 || f >= math.MaxInt64 || dest.OverflowInt(int64(f))
+		}
+	case isUintKind(destType.Kind()):
+		switch {
+		case isIntKind(sourceVal.Kind()):
+			return sourceVal.Int() < 0 || dest.OverflowUint(uint64(sourceVal.Int()))
+		case isUintKind(sourceVal.Kind()):
+			return dest.OverflowUint(sourceVal.Uint())
+		case isFloatKind(sourceVal.Kind()):
+			f := sourceVal.Float()
+			return f < 0 || f >= math.MaxUint64 || dest.OverflowUint(uint64(f))
+		}
+	case isFloatKind(destType.Kind()) && isFloatKind(sourceVal.Kind()):
+		return dest.OverflowFloat(sourceVal.Float())
+	}
+	return false
+}
+
+func isIntKind(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Int64
+}
+
+func isUintKind(kind reflect.Kind) bool {
+	return kind >= reflect.Uint && kind <= reflect.Uintptr
+}
+
+func isFloatKind(kind reflect.Kind) bool {
+	return kind == reflect.Float32 || kind == reflect.Float64
+}
+
+func isNumericKind(kind reflect.Kind) bool {
+	return isIntKind(kind) || isUintKind(kind) || isFloatKind(kind)
+}
+
+// mapSlice maps the elements of the slice or array sourceVal into a new slice
+// of the type of destVal. Every element is mapped with mapValues, so pointer
+// elements are dereferenced or allocated as needed, and a nil pointer maps to
+// the zero value of a non-pointer element type.
 func mapSlice(sourceVal, destVal reflect.Value, opts mapOptions) {
 	destType := destVal.Type()
+	if opts.sliceFilter != nil {
+		sourceVal = filterSlice(sourceVal, opts.sliceFilter)
+	}
 	length := sourceVal.Len()
-	target := reflect.MakeSlice(destType, length, length)
+	if opts.canCopyElements(sourceVal.Type().Elem(), destType.Elem()) {
+		target := reflect.MakeSlice(destType, length, length)
+		reflect.Copy(target, sourceVal)
+		destVal.Set(target)
+		return
+	}
+	if opts.canConvertElements(sourceVal.Type().Elem(), destType.Elem()) {
+		destVal.Set(convertScalarSlice(sourceVal, destType))
+		return
+	}
+	target, preserved := makeTargetSlice(destVal, length, opts)
 	for j := 0; j < length; j++ {
+		if j%contextCheckInterval == 0 {
+			checkContext(opts)
+		}
+		elemOpts := opts
+		elemOpts.sourcePath = indexPath(opts.sourcePath, j)
+		elemOpts.destPath = indexPath(opts.destPath, j)
+		if j < preserved {
+			mapValues(sourceVal.Index(j), target.Index(j), elemOpts)
+			continue
+		}
 		val := reflect.New(destType.Elem()).Elem()
-		mapValues(sourceVal.Index(j), val, opts)
+		mapValues(sourceVal.Index(j), val, elemOpts)
 		target.Index(j).Set(val)
 	}
 
@@ -101,14 +513,203 @@ func mapSlice(sourceVal, destVal reflect.Value, opts mapOptions) {
 	destVal.Set(target)
 }
 
+// canCopyElements returns true if mapping a slice element of sourceType to
+// destType would just assign it, so the elements of a whole slice can be
+// copied at once. This is the case for identical types, unless they hold
+// pointers, which are cloned, or options change how they are mapped.
+func (o mapOptions) canCopyElements(sourceType, destType reflect.Type) bool {
+	if sourceType != destType || containsPointers(destType) || o.deepCopy || !o.allowsFastPath() {
+		return false
+	}
+	_, hasConverter := o.converter(sourceType, destType)
+	return !hasConverter
+}
+
+func isSliceOrArray(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice || t.Kind() == reflect.Array
+}
+
+// canConvertElements returns true if mapping a slice element of sourceType to
+// destType would just convert it, so the elements of a whole slice can be
+// converted without mapping each of them.
+func (o mapOptions) canConvertElements(sourceType, destType reflect.Type) bool {
+	if o.overflowCheck || o.strictTypes || !isScalarConversion(sourceType, destType) {
+		return false
+	}
+	_, hasConverter := o.converter(sourceType, destType)
+	return !hasConverter
+}
+
+// unwrapFirstElement maps the first element of the slice sourceVal into
+// destVal. An empty slice maps to the zero value.
+func unwrapFirstElement(sourceVal, destVal reflect.Value, opts mapOptions) {
+	if sourceVal.Len() == 0 {
+		destVal.Set(reflect.Zero(destVal.Type()))
+		return
+	}
+	elemOpts := opts
+	elemOpts.sourcePath = indexPath(opts.sourcePath, 0)
+	mapValues(sourceVal.Index(0), destVal, elemOpts)
+}
+
+// wrapScalar maps the scalar sourceVal into the only field of the struct
+// destVal that can hold it. See WithScalarWrapping.
+func wrapScalar(sourceVal, destVal reflect.Value, opts mapOptions) {
+	index := opts.scalarWrapField(sourceVal.Type(), destVal.Type())
+	fieldOpts := opts
+	fieldOpts.destPath = joinPath(opts.destPath, destVal.Type().Field(index).Name)
+	mapValues(sourceVal, destVal.Field(index), fieldOpts)
+}
+
+// scalarWrapField returns the index of the only field of the struct type
+// destType that can hold a value of the scalar type sourceType. It panics if
+// there is no such field, or more than one.
+func (o mapOptions) scalarWrapField(sourceType, destType reflect.Type) int {
+	var fields []string
+	index := 0
+	for i := 0; i < destType.NumField(); i++ {
+		field := destType.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		if _, hasConverter := o.converter(sourceType, field.Type); hasConverter || isScalarConversion(sourceType, field.Type) {
+			fields = append(fields, field.Name)
+			index = i
+		}
+	}
+	switch len(fields) {
+	case 0:
+		panic(fmt.Sprintf("cannot wrap %v in %v: no field can hold it", sourceType, destType))
+	case 1:
+		return index
+	default:
+		panic(fmt.Sprintf("cannot wrap %v in %v: fields [%s] can all hold it", sourceType, destType, strings.Join(fields, ", ")))
+	}
+}
+
+// mapArray maps the elements of a slice or array to the array destVal. The
+// lengths must be equal, unless arrays are resized, in which case excess
+// source elements are dropped and missing ones are left at the zero value.
+func mapArray(sourceVal, destVal reflect.Value, opts mapOptions) {
+	length := sourceVal.Len()
+	if length != destVal.Len() {
+		if !opts.resizeArrays {
+			panic(fmt.Sprintf("cannot map %d elements to %v", length, destVal.Type()))
+		}
+		if length > destVal.Len() {
+			length = destVal.Len()
+		}
+	}
+	target := reflect.New(destVal.Type()).Elem()
+	for j := 0; j < length; j++ {
+		if j%contextCheckInterval == 0 {
+			checkContext(opts)
+		}
+		elemOpts := opts
+		elemOpts.sourcePath = indexPath(opts.sourcePath, j)
+		elemOpts.destPath = indexPath(opts.destPath, j)
+		mapValues(sourceVal.Index(j), target.Index(j), elemOpts)
+	}
+	destVal.Set(target)
+}
+
+// filterSlice returns a slice holding the elements of sourceVal for which
+// filter returns true.
+func filterSlice(sourceVal reflect.Value, filter func(interface{}) bool) reflect.Value {
+	filtered := reflect.MakeSlice(reflect.SliceOf(sourceVal.Type().Elem()), 0, sourceVal.Len())
+	for j := 0; j < sourceVal.Len(); j++ {
+		if elem := sourceVal.Index(j); filter(elem.Interface()) {
+			filtered = reflect.Append(filtered, elem)
+		}
+	}
+	return filtered
+}
+
+// isLosslessConversion returns true if values of sourceType can be converted to
+// destType without changing their meaning. This is the case for assignable
+// types, and for types of the same kind, e.g. a named type and its underlying
+// type.
+func isLosslessConversion(sourceType, destType reflect.Type) bool {
+	return sourceType.AssignableTo(destType) || sourceType.Kind() == destType.Kind()
+}
+
+// isScalarConversion returns true if values of sourceType can be converted
+// to destType with a plain Go conversion that does not change the meaning of
+// the value, i.e. between numbers, between strings or between booleans.
+func isScalarConversion(sourceType, destType reflect.Type) bool {
+	sourceKind, destKind := sourceType.Kind(), destType.Kind()
+	switch {
+	case isNumericKind(sourceKind) && isNumericKind(destKind):
+		return true
+	case sourceKind == reflect.String && destKind == reflect.String:
+		return true
+	case sourceKind == reflect.Bool && destKind == reflect.Bool:
+		return true
+	}
+	return false
+}
+
+// convertScalarSlice converts each element of the slice or array sourceVal to
+// the element type of destType, without going through the full mapping of
+// every element.
+func convertScalarSlice(sourceVal reflect.Value, destType reflect.Type) reflect.Value {
+	length := sourceVal.Len()
+	target := reflect.MakeSlice(destType, length, length)
+	elemType := destType.Elem()
+	for j := 0; j < length; j++ {
+		target.Index(j).Set(sourceVal.Index(j).Convert(elemType))
+	}
+	return target
+}
+
+// mapNilSlice maps a nil source slice. The destination becomes nil as well,
+// unless nil slices are mapped as empty slices.
+func mapNilSlice(sourceVal, destVal reflect.Value, opts mapOptions) {
+	if sourceVal.Type() != destVal.Type() {
+		verifyArrayTypesAreCompatible(sourceVal, destVal, opts)
+	}
+	if opts.nilSlicesAsEmpty {
+		destVal.Set(reflect.MakeSlice(destVal.Type(), 0, 0))
+	} else {
+		destVal.Set(reflect.Zero(destVal.Type()))
+	}
+}
+
+// makeTargetSlice returns a slice of the given length to map the elements into,
+// along with the number of existing destination elements it holds. Unless
+// existing elements are preserved, this is always a fresh, zeroed slice.
+func makeTargetSlice(destVal reflect.Value, length int, opts mapOptions) (reflect.Value, int) {
+	if !opts.preserveSliceElements {
+		return reflect.MakeSlice(destVal.Type(), length, length), 0
+	}
+	if length <= destVal.Len() {
+		return destVal.Slice(0, length), length
+	}
+	target := reflect.MakeSlice(destVal.Type(), length, length)
+	return target, reflect.Copy(target, destVal)
+}
+
 func verifyArrayTypesAreCompatible(sourceVal, destVal reflect.Value, opts mapOptions) {
 	dummyDest := reflect.New(reflect.PtrTo(destVal.Type()))
-	dummySource := reflect.MakeSlice(sourceVal.Type(), 1, 1)
+	dummySource := reflect.MakeSlice(reflect.SliceOf(sourceVal.Type().Elem()), 1, 1)
 	mapValues(dummySource, dummyDest.Elem(), opts)
 }
 
 func mapFields(sourceVal, destVal reflect.Value, opts mapOptions) {
-	if opts.useSourceMemberList {
+	checkContext(opts)
+	if opts.positionalMatch {
+		mapFieldsByPosition(sourceVal, destVal, opts)
+	} else if !opts.useSourceMemberList && opts.embedsSourceType(sourceVal.Type(), destVal.Type()) {
+		// The destination decorates the source, so the source maps into
+		// the embedded field as a whole, and destination fields without a
+		// source field are left unchanged.
+		opts.embedsSource = true
+		for i := 0; i < destVal.NumField(); i++ {
+			mapDestField(sourceVal, destVal, i, opts)
+		}
+	} else if opts.allowsFastPath() {
+		mapFieldsWithPlan(sourceVal, destVal, opts)
+	} else if opts.useSourceMemberList {
 		for i := 0; i < sourceVal.NumField(); i++ {
 			mapSourceField(sourceVal, destVal, i, opts)
 		}
@@ -119,30 +720,78 @@ func mapFields(sourceVal, destVal reflect.Value, opts mapOptions) {
 	}
 }
 
+// embedsSourceType returns true if destType embeds sourceType itself, or a
+// pointer to it, with its fields promoted.
+func (o mapOptions) embedsSourceType(sourceType, destType reflect.Type) bool {
+	for i := 0; i < destType.NumField(); i++ {
+		field := destType.Field(i)
+		if field.Anonymous && (field.Type == sourceType || field.Type == reflect.PtrTo(sourceType)) && o.promotesFields(field) {
+			return true
+		}
+	}
+	return false
+}
+
 func mapDestField(source, destVal reflect.Value, i int, opts mapOptions) {
 	destType := destVal.Type()
 	destTypeField := destType.Field(i)
 	destFieldName := destTypeField.Name
-	sourceFieldName := destFieldName
-
-	if automapperTag, ok := destTypeField.Tag.Lookup("automapper"); ok {
-		if automapperTag == "-" {
+	if opts.ignorePattern != nil && opts.ignorePattern.MatchString(destFieldName) {
+		return
+	}
+	if opts.mask != nil {
+		selected, ok := opts.mask[withoutIndexes(joinPath(opts.destPath, destFieldName))]
+		if !ok {
 			return
 		}
-		sourceFieldName = automapperTag
+		if selected {
+			// The whole field is selected, including all of its fields.
+			opts.mask = nil
+		}
+	}
+	tag := opts.parseTag(destTypeField)
+	if tag.skip {
+		if opts.recordsSkips() {
+			opts.skipField(joinPath(opts.destPath, destFieldName), SkipTagged, "skipping field tagged \"-\"")
+		}
+		// The source field is ignored on purpose, so it counts as used.
+		opts.state.visit(fieldPath(opts.sourcePath, source.Type(), destFieldName), joinPath(opts.destPath, destFieldName))
+		return
+	}
+	sourceFieldName, matched := tag.name, true
+	if opts.tagMatching {
+		sourceFieldName, matched = opts.fieldNameByMappedName(source.Type(), sourceFieldName)
+	}
+	if alias, ok := opts.aliases[withoutIndexes(joinPath(opts.destPath, destFieldName))]; ok {
+		sourceFieldName, matched = alias, true
 	}
 
+	fieldDestPath := joinPath(opts.destPath, destFieldName)
 	defer func() {
 		if r := recover(); r != nil {
-			panic(fmt.Sprintf("Error mapping field: %s. DestType: %v. SourceType: %v. Error: %v", destFieldName, destType, source.Type(), r))
+			opts.recordFailure(fieldDestPath)
+			panicWithFieldContext(destFieldName, destType, source.Type(), r)
 		}
 	}()
 
 	destField := destVal.Field(i)
-	if destType.Field(i).Anonymous {
-		mapValues(source, destField, opts)
+	if opts.unsafeUnexported {
+		destField = exposeUnexported(destField)
+	}
+	if destType.Field(i).Anonymous && tag.name == destTypeField.Name {
+		opts.destPath = joinPath(opts.destPath, destFieldName)
+		if isNilStructPointer(destField) {
+			mapIntoNilEmbeddedPointer(source, destField, opts)
+		} else {
+			mapValues(source, destField, opts)
+		}
+	} else if mapResolvedField(destTypeField, source, destField, fieldDestPath, opts) {
+		return
+	} else if !matched {
+		opts.destPath = fieldDestPath
+		mapMissingSourceField(source, destField, sourceFieldName, tag, opts)
 	} else {
-		mapByFieldName(source, destVal, opts, sourceFieldName, destFieldName)
+		mapByFieldName(source, destVal, opts, sourceFieldName, destFieldName, tag)
 	}
 }
 
@@ -150,65 +799,573 @@ func mapSourceField(source, destVal reflect.Value, i int, opts mapOptions) {
 	sourceType := source.Type()
 	sourceTypeField := sourceType.Field(i)
 	sourceFieldName := sourceTypeField.Name
-	destFieldName := sourceFieldName
-
-	if automapperTag, ok := sourceTypeField.Tag.Lookup("automapper"); ok {
-		if automapperTag == "-" {
-			return
+	if opts.ignorePattern != nil && opts.ignorePattern.MatchString(sourceFieldName) {
+		return
+	}
+	tag := opts.parseTag(sourceTypeField)
+	if tag.skip {
+		if opts.recordsSkips() {
+			opts.skipField(joinPath(opts.sourcePath, sourceFieldName), SkipTagged, "skipping source field tagged \"-\"")
 		}
-		destFieldName = automapperTag
+		return
+	}
+	destFieldName, matched := tag.name, true
+	if opts.tagMatching {
+		destFieldName, matched = opts.fieldNameByMappedName(destVal.Type(), destFieldName)
 	}
 
 	defer func() {
 		if r := recover(); r != nil {
-			panic(fmt.Sprintf("Error mapping field: %s. DestType: %v. SourceType: %v. Error: %v", sourceFieldName, destVal.Type(), sourceType, r))
+			panicWithFieldContext(sourceFieldName, destVal.Type(), sourceType, r)
 		}
 	}()
 
 	sourceField := source.Field(i)
-	if sourceType.Field(i).Anonymous {
+	if opts.unsafeUnexported {
+		sourceField = exposeUnexported(sourceField)
+	}
+	if sourceType.Field(i).Anonymous && tag.name == sourceTypeField.Name {
+		if isNilStructPointer(sourceField) || sourceField.Kind() == reflect.Interface && sourceField.IsNil() {
+			// There are no promoted fields to map.
+			if opts.recordsSkips() {
+				opts.skipField(joinPath(opts.sourcePath, sourceFieldName), SkipNilEmbedded, "embedded source is nil, skipping its fields")
+			}
+			return
+		}
+		opts.sourcePath = joinPath(opts.sourcePath, sourceFieldName)
 		mapValues(sourceField, destVal, opts)
+	} else if !matched {
+		panic(fmt.Sprintf("no destination field '%s'", destFieldName))
 	} else {
-		mapByFieldName(source, destVal, opts, sourceFieldName, destFieldName)
+		mapByFieldName(source, destVal, opts, sourceFieldName, destFieldName, tag)
 	}
 }
 
-func mapByFieldName(source, destVal reflect.Value, opts mapOptions, sourceFieldName, destFieldName string) {
-	destField := destVal.FieldByName(destFieldName)
-	if valueIsContainedInNilEmbeddedType(source, sourceFieldName) {
-		return
+// mapResolvedField maps the value the resolver supplies for destTypeField into
+// destField, where an invalid value maps to the zero value. It returns false
+// if there is no resolver, or if it supplies no value. A panic raised by the
+// resolver is recovered like any other hook.
+func mapResolvedField(destTypeField reflect.StructField, source, destField reflect.Value, destPath string, opts mapOptions) (resolved bool) {
+	if opts.resolver == nil {
+		return false
 	}
-	sourceField := source.FieldByName(sourceFieldName)
-	if (sourceField == reflect.Value{}) {
-		if destField.Kind() == reflect.Struct {
-			mapValues(source, destField, opts)
-			return
-		} else {
-			for i := 0; i < source.NumField(); i++ {
-				if source.Field(i).Kind() != reflect.Struct {
-					continue
+	opts.destPath = destPath
+	if opts.recoverHooks {
+		defer recoverHook(destField, opts)
+	}
+	// A recovered panic leaves the field at its zero value, so the field
+	// counts as resolved then.
+	resolved = true
+	var sourceVal reflect.Value
+	callHook(func() { sourceVal, resolved = opts.resolver(destTypeField, source) })
+	if !resolved {
+		return false
+	}
+	if !sourceVal.IsValid() {
+		destField.Set(reflect.Zero(destField.Type()))
+		return true
+	}
+	mapValues(sourceVal, destField, opts)
+	return true
+}
+
+// isNilStructPointer returns true for a nil pointer to a struct.
+func isNilStructPointer(val reflect.Value) bool {
+	return val.Kind() == reflect.Ptr && val.IsNil() && val.Type().Elem().Kind() == reflect.Struct
+}
+
+// mapIntoNilEmbeddedPointer maps source into a new struct for the nil embedded
+// pointer destField. The pointer stays nil if the struct only received zero
+// values, as there was nothing to map then.
+func mapIntoNilEmbeddedPointer(source, destField reflect.Value, opts mapOptions) {
+	val := reflect.New(destField.Type().Elem())
+	mapValues(source, val.Elem(), opts)
+	if !opts.isZero(val.Elem()) {
+		destField.Set(val)
+	}
+}
+
+// allocatedFieldByName returns the field of the struct destVal with the given
+// name, like FieldByName. Nil embedded pointers that the field is promoted
+// through are allocated, so that the field can be set.
+func allocatedFieldByName(destVal reflect.Value, name string) reflect.Value {
+	structField, ok := destVal.Type().FieldByName(name)
+	if !ok {
+		return reflect.Value{}
+	}
+	field := destVal
+	for i, index := range structField.Index {
+		if i > 0 && field.Kind() == reflect.Ptr {
+			if field.IsNil() {
+				if !field.CanSet() {
+					return reflect.Value{}
 				}
-				if sourceField = source.Field(i).FieldByName(sourceFieldName); (sourceField != reflect.Value{}) {
-					break
+				field.Set(reflect.New(field.Type().Elem()))
+			}
+			field = field.Elem()
+		}
+		field = field.Field(index)
+	}
+	return field
+}
+
+func mapByFieldName(source, destVal reflect.Value, opts mapOptions, sourceFieldName, destFieldName string, tag fieldTag) {
+	var destField reflect.Value
+	destOpts := opts
+	if isPath(destFieldName) {
+		var destPath string
+		destField, destPath = lookupDestField(destVal, destFieldName)
+		destOpts.destPath = joinPath(opts.destPath, destPath)
+	} else {
+		destField = allocatedFieldByName(destVal, destFieldName)
+		if !destField.IsValid() {
+			if name, ok := opts.affixedFieldName(destVal.Type(), destFieldName); ok {
+				destFieldName = name
+				destField = allocatedFieldByName(destVal, destFieldName)
+			}
+		}
+		destOpts.destPath = fieldPath(opts.destPath, destVal.Type(), destFieldName)
+	}
+	if opts.setters && !destField.CanSet() {
+		if setter, ok := lookupSetter(destVal, destFieldName); ok {
+			mapToSetter(source, setter, sourceFieldName, tag, destOpts)
+			return
+		}
+	}
+	var namedConverter ConverterFunc
+	if tag.converterName != "" {
+		var ok bool
+		if namedConverter, ok = opts.namedConverters[tag.converterName]; !ok {
+			panic(fmt.Sprintf("no converter named '%s'", tag.converterName))
+		}
+	}
+	sourceFieldName = opts.candidateFieldName(source, sourceFieldName)
+	sourceField, sourcePath, found := lookupField(source, sourceFieldName)
+	if !found {
+		if name, ok := opts.affixedFieldName(source.Type(), sourceFieldName); ok {
+			sourceField, sourcePath, found = lookupField(source, name)
+		}
+	}
+	if !found && opts.getters {
+		sourceField, sourcePath, found = lookupGetter(source, sourceFieldName)
+	}
+	if !found && destField.Kind() == reflect.Struct {
+		if opts.logger != nil {
+			opts.logger(destOpts.destPath, fmt.Sprintf("no source field '%s', mapping the whole source", sourceFieldName))
+		}
+		mapValues(source, destField, destOpts)
+		return
+	}
+	if !found {
+		sourceField, sourcePath, found = lookupNestedField(source, sourceFieldName)
+	}
+	if !found && embedsNilInterface(source) {
+		// The field may be promoted through the nil interface, which has no
+		// value, like a field promoted through a nil embedded pointer.
+		if opts.recordsSkips() {
+			opts.skipField(destOpts.destPath, SkipNilEmbedded, "no source field '%s', source embeds a nil interface, skipping", sourceFieldName)
+		}
+		return
+	}
+	if !found {
+		mapMissingSourceField(source, destField, sourceFieldName, tag, destOpts)
+		return
+	}
+	if !sourceField.IsValid() {
+		// The field is promoted through a nil embedded pointer, so there is
+		// no value to map.
+		if opts.recordsSkips() {
+			opts.skipField(destOpts.destPath, SkipNilEmbedded, "source field '%s' is promoted through a nil pointer, skipping", sourceFieldName)
+		}
+		return
+	}
+	if opts.unsafeUnexported {
+		sourceField, destField = exposeUnexported(sourceField), exposeUnexported(destField)
+	}
+	destOpts.sourcePath = joinPath(opts.sourcePath, sourcePath)
+	if opts.logger != nil && sourcePath != sourceFieldName {
+		opts.logger(destOpts.destPath, fmt.Sprintf("resolved source field '%s' to '%s'", sourceFieldName, sourcePath))
+	}
+	if tag.omitEmpty && opts.isZero(sourceField) {
+		if opts.recordsSkips() {
+			opts.skipField(destOpts.destPath, SkipEmpty, "source value is empty, skipping field tagged omitempty")
+		}
+		return
+	}
+	if tag.hasDefault && opts.isZero(sourceField) {
+		if opts.logger != nil {
+			opts.logger(destOpts.destPath, "source value is empty, using default value")
+		}
+		setDefault(destField, tag.defaultValue)
+		return
+	}
+	if transform, ok := opts.fieldTransforms[withoutIndexes(destOpts.destPath)]; ok {
+		mapTransformed(sourceField, destField, transform, destOpts)
+		return
+	}
+	if namedConverter != nil {
+		convertLeaf(sourceField, destField, destOpts, wrapConverterFunc(destField.Type(), namedConverter))
+		return
+	}
+	if tag.timeUnit != 0 {
+		mapUnixTime(sourceField, destField, tag.timeUnit, destOpts)
+		return
+	}
+	mapValues(sourceField, destField, destOpts)
+}
+
+// mapMissingSourceField handles the field destField, for which source has no
+// field named sourceFieldName. The field receives the default value of its
+// tag, or is left unchanged if the destination embeds the source. Otherwise
+// the mapping fails.
+func mapMissingSourceField(source, destField reflect.Value, sourceFieldName string, tag fieldTag, opts mapOptions) {
+	if tag.hasDefault {
+		if opts.recordsSkips() {
+			opts.skipField(opts.destPath, SkipMissingSource, "no source field '%s', using default value", sourceFieldName)
+		}
+		setDefault(destField, tag.defaultValue)
+		return
+	}
+	if opts.embedsSource {
+		if opts.recordsSkips() {
+			opts.skipField(opts.destPath, SkipMissingSource, "no source field '%s', destination embeds the source", sourceFieldName)
+		}
+		return
+	}
+	panic(fmt.Sprintf("no source field '%s'; available: [%s]", sourceFieldName, strings.Join(exportedFieldNames(source.Type()), ", ")))
+}
+
+// mapTransformed maps sourceField into a value of the destination type, and
+// sets destField to the result of passing that value through transform.
+func mapTransformed(sourceField, destField reflect.Value, transform func(interface{}) interface{}, opts mapOptions) {
+	val := reflect.New(destField.Type()).Elem()
+	mapValues(sourceField, val, opts)
+	if opts.recoverHooks {
+		defer recoverHook(destField, opts)
+	}
+	var result reflect.Value
+	callHook(func() { result = reflect.ValueOf(transform(val.Interface())) })
+	if !result.IsValid() {
+		destField.Set(reflect.Zero(destField.Type()))
+		return
+	}
+	if !result.Type().AssignableTo(destField.Type()) {
+		panic(fmt.Sprintf("field transform returned %v, which is not assignable to %v", result.Type(), destField.Type()))
+	}
+	destField.Set(result)
+}
+
+// lookupField returns the field of the struct source with the given name,
+// along with its path. Promoted fields are resolved like in Go, so a field of
+// source itself always wins over a field of an embedded struct. The name may
+// be a path, see parsePath, to select a field explicitly. If the field exists
+// but is reached through a nil pointer, found is true but field is the zero
+// Value.
+func lookupField(source reflect.Value, name string) (field reflect.Value, path string, found bool) {
+	field = source
+	for i, step := range parsePath(name) {
+		if i > 0 {
+			field = concreteValue(field)
+			if valueIsNil(field) {
+				return reflect.Value{}, path, true
+			}
+			field = reflect.Indirect(field)
+		}
+		if field.Kind() != reflect.Struct {
+			return reflect.Value{}, "", false
+		}
+		segment, ok := step.fieldName(field.Type())
+		if !ok {
+			return reflect.Value{}, "", false
+		}
+		structField, _ := field.Type().FieldByName(segment)
+		if valueIsContainedInNilEmbeddedType(field, segment) {
+			return reflect.Value{}, path, true
+		}
+		path = fieldPath(path, field.Type(), segment)
+		field = field.FieldByIndex(structField.Index)
+	}
+	return field, path, true
+}
+
+// lookupDestField returns the field of the struct destVal at the given path,
+// see parsePath, along with the path of the field names. Nil pointers on the
+// way are allocated, so that the field can be set.
+func lookupDestField(destVal reflect.Value, name string) (field reflect.Value, path string) {
+	field = destVal
+	for i, step := range parsePath(name) {
+		if i > 0 {
+			for field.Kind() == reflect.Ptr {
+				if field.IsNil() {
+					field.Set(reflect.New(field.Type().Elem()))
 				}
+				field = field.Elem()
 			}
 		}
+		if field.Kind() != reflect.Struct {
+			panic(fmt.Sprintf("no destination field '%s'", name))
+		}
+		segment, ok := step.fieldName(field.Type())
+		if !ok {
+			panic(fmt.Sprintf("no destination field '%s'", name))
+		}
+		path = fieldPath(path, field.Type(), segment)
+		field = allocatedFieldByName(field, segment)
+	}
+	return field, path
+}
+
+// pathStep is a single step of a field path. It selects a field by name, or
+// if byType is set, the field of a struct whose type has the name.
+type pathStep struct {
+	name   string
+	byType bool
+}
+
+// parsePath splits a field path into its steps. A path is a list of field
+// names separated by dots, e.g. "Address.Street". A name followed by a slash
+// instead of a dot names the type of the field to select, e.g.
+// "BillingAddress/Street" selects Street of the field of type BillingAddress.
+func parsePath(path string) []pathStep {
+	var steps []pathStep
+	for _, segment := range strings.Split(path, ".") {
+		parts := strings.Split(segment, "/")
+		for i, part := range parts {
+			steps = append(steps, pathStep{name: part, byType: i < len(parts)-1})
+		}
+	}
+	return steps
+}
+
+// isPath returns true if name is a path of more than one field name.
+func isPath(name string) bool {
+	return strings.ContainsAny(name, "./")
+}
+
+// fieldName returns the name of the field of struct type t selected by the
+// step. Fields promoted from embedded structs are found by name, but not by
+// type. It panics if more than one field has the type named by the step.
+func (step pathStep) fieldName(t reflect.Type) (string, bool) {
+	if !step.byType {
+		_, ok := t.FieldByName(step.name)
+		return step.name, ok
+	}
+	var candidates []string
+	for i := 0; i < t.NumField(); i++ {
+		if field := t.Field(i); derefType(field.Type).Name() == step.name {
+			candidates = append(candidates, field.Name)
+		}
+	}
+	if len(candidates) > 1 {
+		panic(fmt.Sprintf("ambiguous field type '%s' in %v; found in [%s]", step.name, t, strings.Join(candidates, ", ")))
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+	return candidates[0], true
+}
+
+// lookupNestedField searches the struct fields of source for a field with the
+// given name. It panics if more than one of them has such a field.
+func lookupNestedField(source reflect.Value, name string) (field reflect.Value, path string, found bool) {
+	var candidates []string
+	for i := 0; i < source.NumField(); i++ {
+		nested := concreteValue(source.Field(i))
+		if nested.Kind() != reflect.Struct {
+			continue
+		}
+		if nestedField, nestedPath, ok := lookupField(nested, name); ok {
+			fieldName := source.Type().Field(i).Name
+			field, path, found = nestedField, joinPath(fieldName, nestedPath), true
+			candidates = append(candidates, fieldName)
+		}
+	}
+	if len(candidates) > 1 {
+		panic(fmt.Sprintf("ambiguous source field '%s'; found in [%s], use a dotted path to select one", name, strings.Join(candidates, ", ")))
+	}
+	return field, path, found
+}
+
+// isStringBytesPair returns true when one of the types is a string and the
+// other is a byte slice, in either direction.
+func isStringBytesPair(sourceType, destType reflect.Type) bool {
+	return (sourceType.Kind() == reflect.String && isByteSlice(destType)) ||
+		(isByteSlice(sourceType) && destType.Kind() == reflect.String)
+}
+
+func isByteSlice(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8
+}
+
+// isByteArrayPair returns true for a byte array and a byte slice, or two byte
+// arrays, with the same element type.
+func isByteArrayPair(sourceType, destType reflect.Type) bool {
+	isBytes := func(t reflect.Type) bool {
+		return (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() == reflect.Uint8
+	}
+	return isBytes(sourceType) && isBytes(destType) && sourceType.Elem() == destType.Elem() &&
+		(sourceType.Kind() == reflect.Array || destType.Kind() == reflect.Array)
+}
+
+// mapBytes copies the bytes of sourceVal into destVal, which is what keys and
+// hashes need. Like mapArray, a destination array must have the length of the
+// source, unless arrays are resized, in which case excess source bytes are
+// dropped and missing ones are left at zero. A nil slice maps to the zero
+// array. A destination slice gets the length of the source array.
+func mapBytes(sourceVal, destVal reflect.Value, opts mapOptions) {
+	destType := destVal.Type()
+	if sourceVal.Kind() == reflect.Slice && sourceVal.IsNil() {
+		destVal.Set(reflect.Zero(destType))
+		return
+	}
+	if destType.Kind() == reflect.Slice {
+		target := reflect.MakeSlice(destType, sourceVal.Len(), sourceVal.Len())
+		reflect.Copy(target, sourceVal)
+		destVal.Set(target)
+		return
+	}
+	if sourceVal.Len() != destType.Len() && !opts.resizeArrays {
+		panic(fmt.Sprintf("cannot map %d bytes to %v", sourceVal.Len(), destType))
+	}
+	target := reflect.New(destType).Elem()
+	reflect.Copy(target, sourceVal)
+	destVal.Set(target)
+}
+
+// recordFailure records path as the path of the field that failed to map, if
+// the failed path is wanted and no field inside of it failed before.
+func (o mapOptions) recordFailure(path string) {
+	if o.failedPath != nil && *o.failedPath == "" {
+		*o.failedPath = path
+	}
+}
+
+// panicWithFieldContext panics with the error r, decorated with information
+// about the field being mapped. Errors are wrapped, so the original error can
+// still be found with errors.Is and errors.As.
+func panicWithFieldContext(fieldName string, destType, sourceType reflect.Type, r interface{}) {
+	const format = "Error mapping field: %s. DestType: %v. SourceType: %v. Error: %"
+	if err, ok := r.(error); ok {
+		panic(fmt.Errorf(format+"w", fieldName, destType, sourceType, err))
+	}
+	panic(fmt.Sprintf(format+"v", fieldName, destType, sourceType, r))
+}
+
+// joinPath appends a field name to a dotted field path.
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// indexPath appends a slice index to a field path.
+func indexPath(prefix string, index int) string {
+	return prefix + "[" + strconv.Itoa(index) + "]"
+}
+
+// withoutIndexes removes the slice indexes from a field path, so
+// "Children[0].Name" becomes "Children.Name".
+func withoutIndexes(path string) string {
+	if !strings.Contains(path, "[") {
+		return path
+	}
+	var b strings.Builder
+	inIndex := false
+	for _, r := range path {
+		switch {
+		case r == '[':
+			inIndex = true
+		case r == ']':
+			inIndex = false
+		case !inIndex:
+			b.WriteRune(r)
+		}
 	}
-	mapValues(sourceField, destField, opts)
+	return b.String()
+}
+
+// fieldPath appends the path to the named field of a struct type. For a
+// promoted field, the path includes the embedded fields it is promoted
+// through.
+func fieldPath(prefix string, t reflect.Type, name string) string {
+	structField, ok := t.FieldByName(name)
+	if !ok {
+		return joinPath(prefix, name)
+	}
+	path := prefix
+	for _, i := range structField.Index {
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		path = joinPath(path, t.Field(i).Name)
+		t = t.Field(i).Type
+	}
+	return path
+}
+
+// exportedFieldNames returns the names of the exported fields of a struct
+// type, in declaration order.
+func exportedFieldNames(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		if field := t.Field(i); field.PkgPath == "" {
+			names = append(names, field.Name)
+		}
+	}
+	return names
+}
+
+// concreteValue returns the value held by a non-nil interface value. Any
+// other value is returned as is.
+func concreteValue(value reflect.Value) reflect.Value {
+	if value.Kind() == reflect.Interface && !value.IsNil() {
+		return value.Elem()
+	}
+	return value
+}
+
+// isZero returns true if value is empty, as defined by WithIsZero for its
+// type, or else by being the zero value of its type.
+func (o mapOptions) isZero(value reflect.Value) bool {
+	if isZero, ok := o.isZeroFuncs[value.Type()]; ok {
+		return isZero(value.Interface())
+	}
+	return value.IsZero()
 }
 
 func valueIsNil(value reflect.Value) bool {
 	return value.Type().Kind() == reflect.Ptr && value.IsNil()
 }
 
-func valueIsContainedInNilEmbeddedType(source reflect.Value, fieldName string) bool {
-	structField, _ := source.Type().FieldByName(fieldName)
-	ix := structField.Index
-	if len(structField.Index) > 1 {
-		parentField := source.FieldByIndex(ix[:len(ix)-1])
-		if valueIsNil(parentField) {
+// embedsNilInterface returns true if the struct source embeds an interface
+// that is nil. The fields such an interface would promote are unknown.
+func embedsNilInterface(source reflect.Value) bool {
+	for i := 0; i < source.NumField(); i++ {
+		if field := source.Field(i); source.Type().Field(i).Anonymous && field.Kind() == reflect.Interface && field.IsNil() {
 			return true
 		}
 	}
 	return false
 }
+
+// valueIsContainedInNilEmbeddedType returns true if the named field of source
+// is promoted through an embedded pointer that is nil, at any level of
+// embedding. Such a field has no value, and reflect panics when accessing it.
+func valueIsContainedInNilEmbeddedType(source reflect.Value, fieldName string) bool {
+	structField, ok := source.Type().FieldByName(fieldName)
+	if !ok {
+		return false
+	}
+	parent := source
+	for _, i := range structField.Index[:len(structField.Index)-1] {
+		parent = parent.Field(i)
+		if parent.Kind() == reflect.Ptr {
+			if parent.IsNil() {
+				return true
+			}
+			parent = parent.Elem()
+		}
+	}
+	return false
+}
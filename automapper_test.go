@@ -3,6 +3,12 @@
 package automapper
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
 	"testing"
 	"time"
 
@@ -17,6 +23,18 @@ func TestPanicWhenDestIsNotPointer(t *testing.T) {
 	t.Error("Should have panicked")
 }
 
+func TestPanicWhenDestIsNilPointer(t *testing.T) {
+	defer func() {
+		r := recover()
+		assert.Contains(t, r, "nil pointer")
+	}()
+	source := SourceTypeA{}
+	var dest *DestTypeA
+	MapToDestination(source, dest)
+
+	t.Error("Should have panicked")
+}
+
 func TestDestinationIsUpdatedFromSource(t *testing.T) {
 	source, dest := SourceTypeA{Foo: 42}, DestTypeA{}
 	MapToDestination(source, &dest)
@@ -30,6 +48,33 @@ func TestDestinationIsUpdatedFromSourceWhenSourcePassedAsPtr(t *testing.T) {
 	assert.Equal(t, "Bar", dest.Bar)
 }
 
+func TestDestinationIsPointerToNilPointer(t *testing.T) {
+	source := SourceTypeA{42, "Bar"}
+	var dest *DestTypeA
+	MapToDestination(source, &dest)
+	assert.Equal(t, &DestTypeA{42, "Bar"}, dest)
+}
+
+func TestDestinationIsPointerToPointer(t *testing.T) {
+	source := SourceTypeA{42, "Bar"}
+	existing := &DestTypeA{}
+	dest := existing
+	MapToDestination(source, &dest)
+	assert.True(t, existing == dest)
+	assert.Equal(t, 42, dest.Foo)
+}
+
+func TestSourceIsPointerToPointer(t *testing.T) {
+	source := &SourceTypeA{42, "Bar"}
+	dest := DestTypeA{}
+	MapToDestination(&source, &dest)
+	assert.Equal(t, DestTypeA{42, "Bar"}, dest)
+
+	var nilSource *SourceTypeA
+	MapToDestination(&nilSource, &dest)
+	assert.Equal(t, DestTypeA{}, dest)
+}
+
 func TestWithNestedTypes(t *testing.T) {
 	source := struct {
 		Baz   string
@@ -85,6 +130,91 @@ func TestWithSliceTypes(t *testing.T) {
 	assert.Equal(t, 2, dest.Children[1].Foo)
 }
 
+func TestWithPointerSliceToValueSlice(t *testing.T) {
+	source := struct {
+		Children []*SourceTypeA
+	}{}
+	dest := struct {
+		Children []DestTypeA
+	}{}
+	source.Children = []*SourceTypeA{
+		&SourceTypeA{Foo: 1},
+		nil,
+		&SourceTypeA{Foo: 3, Bar: "Bar"}}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, []DestTypeA{{Foo: 1}, {}, {Foo: 3, Bar: "Bar"}}, dest.Children)
+}
+
+func TestWithScalarPointerSliceToValueSlice(t *testing.T) {
+	one := 1
+	source := struct {
+		Values []*int
+	}{[]*int{&one, nil}}
+	dest := struct {
+		Values []int64
+	}{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, []int64{1, 0}, dest.Values)
+}
+
+func TestWithConvertibleScalarSlices(t *testing.T) {
+	type MyString string
+	type MyStrings []MyString
+	source := struct {
+		Ints    []int
+		Floats  []int
+		Strings []string
+		Named   []string
+		Empty   []int
+	}{
+		Ints:    []int{1, 2},
+		Floats:  []int{3},
+		Strings: []string{"a", "b"},
+		Named:   []string{"c"},
+		Empty:   []int{},
+	}
+	dest := struct {
+		Ints    []int64
+		Floats  []float64
+		Strings []MyString
+		Named   MyStrings
+		Empty   []uint
+	}{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, []int64{1, 2}, dest.Ints)
+	assert.Equal(t, []float64{3}, dest.Floats)
+	assert.Equal(t, []MyString{"a", "b"}, dest.Strings)
+	assert.Equal(t, MyStrings{"c"}, dest.Named)
+	assert.Equal(t, []uint{}, dest.Empty)
+}
+
+func TestConverterTakesPrecedenceForScalarSlices(t *testing.T) {
+	round := func(_ context.Context, v interface{}) (interface{}, error) {
+		return int(math.Round(v.(float64))), nil
+	}
+	source := struct{ Values []float64 }{[]float64{1.6, 2.5}}
+	dest := struct{ Values []int }{}
+
+	MapToDestination(&source, &dest, WithConverter(reflect.TypeOf(0.0), reflect.TypeOf(0), round))
+	assert.Equal(t, []int{2, 3}, dest.Values)
+}
+
+func TestWithIncompatibleScalarSlices(t *testing.T) {
+	defer func() { recover() }()
+	source := struct {
+		Values []string
+	}{[]string{"a"}}
+	dest := struct {
+		Values []int
+	}{}
+
+	MapToDestination(&source, &dest)
+	t.Error("Should have panicked")
+}
+
 func TestWithMultiLevelSlices(t *testing.T) {
 	source := struct {
 		Parents []SourceParent
@@ -133,268 +263,1398 @@ func TestWhenSourceIsMissingField(t *testing.T) {
 	t.Error("Should have panicked")
 }
 
-func TestWithUnnamedFields(t *testing.T) {
+func TestWhenSourceIsMissingFieldReportsAvailableFields(t *testing.T) {
+	defer func() {
+		r := recover()
+		assert.Contains(t, r, "no source field 'UserName'; available: [User, Name, Email]")
+	}()
 	source := struct {
-		Baz string
-		SourceTypeA
+		User, Name, Email string
 	}{}
 	dest := struct {
-		Baz string
-		DestTypeA
+		UserName string
 	}{}
-	source.Baz = "Baz"
-	source.SourceTypeA.Foo = 42
-
 	MapToDestination(&source, &dest)
-	assert.Equal(t, "Baz", dest.Baz)
-	assert.Equal(t, 42, dest.DestTypeA.Foo)
+	t.Error("Should have panicked")
 }
 
-func TestWithPointerFieldsNotNil(t *testing.T) {
+func TestMapInto(t *testing.T) {
+	type destChild struct {
+		Foo int
+		Baz string `automapper:"-"`
+	}
 	source := struct {
-		Foo *SourceTypeA
-	}{}
+		Child    *struct{ Foo int }
+		Children []struct{ Foo int }
+	}{
+		Child:    &struct{ Foo int }{1},
+		Children: []struct{ Foo int }{{2}},
+	}
+	child := &destChild{Baz: "a"}
 	dest := struct {
-		Foo *DestTypeA
-	}{}
-	source.Foo = nil
+		Child    *destChild
+		Children []destChild
+	}{
+		Child:    child,
+		Children: []destChild{{Baz: "b"}, {Baz: "c"}},
+	}
 
-	MapToDestination(&source, &dest)
-	assert.Nil(t, dest.Foo)
+	MapInto(&source, &dest)
+	assert.True(t, child == dest.Child)
+	assert.Equal(t, destChild{1, "a"}, *dest.Child)
+	assert.Equal(t, []destChild{{2, "b"}}, dest.Children)
 }
 
-func TestWithPointerFieldsNil(t *testing.T) {
+func TestMapIntoKeepsPointerWhenSourceIsNil(t *testing.T) {
 	source := struct {
-		Foo *SourceTypeA
+		Child *SourceTypeA
 	}{}
 	dest := struct {
-		Foo *DestTypeA
-	}{}
-	source.Foo = &SourceTypeA{Foo: 42}
+		Child *DestTypeA
+	}{&DestTypeA{Foo: 1}}
 
-	MapToDestination(&source, &dest)
-	assert.NotNil(t, dest.Foo)
-	assert.Equal(t, 42, dest.Foo.Foo)
+	MapInto(&source, &dest)
+	assert.Equal(t, 1, dest.Child.Foo)
 }
 
-func TestMapToDestinationPointerToNonPointerTypeWithData(t *testing.T) {
-	source := struct {
-		Foo *SourceTypeA
-	}{}
+func TestMapIntoKeepsPointerOfSameType(t *testing.T) {
+	type child struct{ Foo, Bar int }
+	source := struct{ Child *child }{&child{Foo: 1}}
+	existing := &child{Foo: 2, Bar: 3}
 	dest := struct {
-		Foo DestTypeA
-	}{}
-	source.Foo = &SourceTypeA{Foo: 42}
+		Child *child
+		Baz   int `automapper:"-"`
+	}{Child: existing}
 
-	MapToDestination(&source, &dest)
-	assert.NotNil(t, dest.Foo)
-	assert.Equal(t, 42, dest.Foo.Foo)
+	MapInto(&source, &dest)
+	assert.True(t, existing == dest.Child)
+	assert.Equal(t, child{Foo: 1}, *dest.Child)
 }
 
-func TestMapToDestinationPointerToNonPointerTypeWithoutData(t *testing.T) {
+func TestMapSliceInto(t *testing.T) {
+	dest := []DestTypeA{{Foo: 1}}
+
+	MapSliceInto([]SourceTypeA{{Foo: 2}, {Foo: 3}}, &dest)
+	MapSliceInto(&[]SourceTypeA{{Foo: 4}}, &dest)
+	MapSliceInto([]SourceTypeA(nil), &dest)
+	assert.Equal(t, []DestTypeA{{Foo: 1}, {Foo: 2}, {Foo: 3}, {Foo: 4}}, dest)
+}
+
+func TestMapSliceIntoNonSlicePanics(t *testing.T) {
+	defer func() { recover() }()
+	dest := DestTypeA{}
+
+	MapSliceInto([]SourceTypeA{{Foo: 2}}, &dest)
+	t.Error("Should have panicked")
+}
+
+func TestMapSliceIntoNonSliceSourceFails(t *testing.T) {
+	dest := []DestTypeA{}
+
+	err := SafeMap(func() { MapSliceInto(SourceTypeA{Foo: 2}, &dest) })
+	var incompatible *IncompatibleTypesError
+	if assert.True(t, errors.As(err, &incompatible)) {
+		assert.Equal(t, reflect.TypeOf(SourceTypeA{}), incompatible.SourceType)
+		assert.Equal(t, "cannot map automapper.SourceTypeA to []automapper.DestTypeA: source must be a slice or an array", err.Error())
+	}
+	assert.Error(t, SafeMap(func() { MapSliceInto(nil, &dest) }))
+	assert.Empty(t, dest)
+}
+
+func TestMapWithMask(t *testing.T) {
+	type profile struct {
+		Name, Email string
+	}
 	source := struct {
-		Foo *SourceTypeA
-	}{}
+		ID      int
+		Profile profile
+		Address *profile
+	}{1, profile{"foo", "foo@example.com"}, &profile{"bar", "bar@example.com"}}
 	dest := struct {
-		Foo DestTypeA
-	}{}
-	source.Foo = nil
+		ID      int
+		Profile profile
+		Address *profile
+	}{2, profile{"old", "old@example.com"}, &profile{"old", "old@example.com"}}
+	address := dest.Address
 
-	MapToDestination(&source, &dest)
-	assert.NotNil(t, dest.Foo)
-	assert.Equal(t, 0, dest.Foo.Foo)
+	MapWithMask(&source, &dest, []string{"Profile.Name", "Address.Email"})
+	assert.Equal(t, 2, dest.ID)
+	assert.Equal(t, profile{"foo", "old@example.com"}, dest.Profile)
+	assert.True(t, address == dest.Address)
+	assert.Equal(t, profile{"old", "bar@example.com"}, *dest.Address)
 }
 
-func TestMapToDestinationPointerToAnonymousTypeToFieldName(t *testing.T) {
+func TestMapWithMaskSelectingWholeStruct(t *testing.T) {
 	source := struct {
-		*SourceTypeA
-	}{}
+		Foo   int
+		Child SourceTypeA
+	}{1, SourceTypeA{Foo: 2, Bar: "new"}}
 	dest := struct {
-		Foo int
-	}{}
-	source.SourceTypeA = nil
+		Foo   int
+		Child DestTypeA
+	}{0, DestTypeA{Foo: 3, Bar: "old"}}
 
-	MapToDestination(&source, &dest)
+	MapWithMask(&source, &dest, []string{"Child"})
 	assert.Equal(t, 0, dest.Foo)
+	assert.Equal(t, DestTypeA{Foo: 2, Bar: "new"}, dest.Child)
 }
 
-func TestMapToDestinationPointerToNonPointerTypeWithoutDataAndIncompatibleType(t *testing.T) {
-	defer func() { recover() }()
-	// Just make sure we stil panic
+func TestMapWithAliases(t *testing.T) {
 	source := struct {
-		Foo *SourceTypeA
-	}{}
+		FullName string
+		Street   string
+		Address  struct{ Line1, City string }
+	}{"foo", "bar", struct{ Line1, City string }{"baz", "qux"}}
 	dest := struct {
-		Foo struct {
-			Baz string
-		}
+		Name    string
+		Street  string
+		Address struct{ Street, City string }
 	}{}
-	source.Foo = nil
+	aliases := map[string]string{
+		"Name":           "FullName",
+		"Address.Street": "Line1",
+	}
 
-	MapToDestination(&source, &dest)
-	t.Error("Should have panicked")
+	MapWithAliases(&source, &dest, aliases)
+	assert.Equal(t, "foo", dest.Name)
+	assert.Equal(t, "bar", dest.Street)
+	assert.Equal(t, "baz", dest.Address.Street)
+	assert.Equal(t, "qux", dest.Address.City)
 }
 
-func TestWhenUsingIncompatibleTypes(t *testing.T) {
-	defer func() { recover() }()
+func TestMapWithAliasesToDottedSourcePath(t *testing.T) {
+	source := struct{ Child SourceTypeA }{SourceTypeA{Foo: 42}}
+	dest := struct{ Foo int }{}
+
+	MapWithAliases(&source, &dest, map[string]string{"Foo": "Child.Foo"})
+	assert.Equal(t, 42, dest.Foo)
+}
+
+func TestMapValues(t *testing.T) {
+	source := SourceTypeA{Foo: 42, Bar: "Bar"}
+	dest := DestTypeA{}
+
+	err := MapValues(reflect.ValueOf(source), reflect.ValueOf(&dest).Elem())
+	assert.NoError(t, err)
+	assert.Equal(t, DestTypeA{Foo: 42, Bar: "Bar"}, dest)
+}
+
+func TestMapValuesRequiresSettableDest(t *testing.T) {
+	err := MapValues(reflect.ValueOf(SourceTypeA{}), reflect.ValueOf(DestTypeA{}))
+	assert.EqualError(t, err, "dest of type automapper.DestTypeA must be settable")
+
+	err = MapValues(reflect.Value{}, reflect.ValueOf(&DestTypeA{}).Elem())
+	assert.Error(t, err)
+}
+
+func TestMapValuesReturnsMappingErrors(t *testing.T) {
 	source := struct{ Foo string }{}
 	dest := struct{ Foo int }{}
-	MapToDestination(&source, &dest)
-	t.Error("Should have panicked")
+
+	err := MapValues(reflect.ValueOf(source), reflect.ValueOf(&dest).Elem())
+	assert.Error(t, err)
 }
 
-func TestSetStructOfSameTypeDirectly(t *testing.T) {
-	type FooType struct {
-		time.Time
-	}
-	source := struct {
-		Foo FooType
-	}{FooType{Time: time.Now().UTC()}}
-	dest := struct {
-		Foo FooType
-	}{}
-	MapToDestination(&source, &dest)
-	assert.Equal(t, source.Foo.String(), dest.Foo.String())
+func TestSafeMap(t *testing.T) {
+	source, dest := SourceTypeA{Foo: 42}, DestTypeA{}
+	err := SafeMap(func() { MapToDestination(source, &dest) })
+	assert.NoError(t, err)
+	assert.Equal(t, 42, dest.Foo)
 }
 
-func TestNamedType(t *testing.T) {
-	type SourceType string
-	type DestType string
+func TestSafeMapReturnsPanicAsError(t *testing.T) {
+	source := struct{ Foo string }{}
+	dest := struct{ Foo int }{}
+	err := SafeMap(func() { MapToDestination(&source, &dest) })
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Error mapping field: Foo")
+}
+
+func TestOuterFieldWinsOverEmbeddedField(t *testing.T) {
 	source := struct {
-		Foo SourceType
-	}{"abc"}
+		Foo int
+		SourceTypeA
+	}{Foo: 1, SourceTypeA: SourceTypeA{Foo: 2}}
 	dest := struct {
-		Foo DestType
+		Foo int
 	}{}
+
 	MapToDestination(&source, &dest)
-	assert.Equal(t, string(source.Foo), string(dest.Foo))
+	assert.Equal(t, 1, dest.Foo)
 }
 
-func TestSkip(t *testing.T) {
+func TestSelectEmbeddedFieldWithDottedPath(t *testing.T) {
 	source := struct {
-		Foo string
-	}{"abc"}
+		Foo int
+		SourceTypeA
+	}{Foo: 1, SourceTypeA: SourceTypeA{Foo: 2}}
 	dest := struct {
-		Foo string
-		Bar string `automapper:"-"`
+		Foo int `automapper:"SourceTypeA.Foo"`
 	}{}
+
 	MapToDestination(&source, &dest)
-	assert.Empty(t, dest.Bar)
+	assert.Equal(t, 2, dest.Foo)
 }
 
-func TestMapToDestination(t *testing.T) {
+type BillingAddress struct{ Street string }
+
+type ShippingAddress struct{ Street string }
+
+func TestMapIntoEmbeddedFieldSelectedByType(t *testing.T) {
 	source := struct {
-		Foo string
-	}{"abc"}
+		Billing  string `automapper:"BillingAddress/Street"`
+		Shipping string `automapper:"ShippingAddress.Street"`
+	}{"foo", "bar"}
 	dest := struct {
-		Bar string `automapper:"Foo"`
+		BillingAddress
+		*ShippingAddress
 	}{}
-	MapToDestination(&source, &dest)
-	assert.Equal(t, source.Foo, dest.Bar)
+
+	MapFromSource(&source, &dest)
+	assert.Equal(t, "foo", dest.BillingAddress.Street)
+	assert.Equal(t, "bar", dest.ShippingAddress.Street)
 }
 
-func TestMapFromSource(t *testing.T) {
+func TestMapIntoNestedFieldSelectedByType(t *testing.T) {
 	source := struct {
-		Foo string `automapper:"Bar"`
-	}{"abc"}
+		Street string `automapper:"BillingAddress/Street"`
+	}{"foo"}
 	dest := struct {
-		Bar string
+		Home    ShippingAddress
+		Invoice *BillingAddress
 	}{}
+
 	MapFromSource(&source, &dest)
-	assert.Equal(t, source.Foo, dest.Bar)
+	assert.Equal(t, "foo", dest.Invoice.Street)
+	assert.Equal(t, "", dest.Home.Street)
 }
 
-func TestMapSourceField_DestContainsUnmappedFields(t *testing.T) {
+func TestMapFromFieldSelectedByType(t *testing.T) {
 	source := struct {
-		Foo string
-	}{"abc"}
+		Home    ShippingAddress
+		Invoice BillingAddress
+	}{ShippingAddress{"foo"}, BillingAddress{"bar"}}
 	dest := struct {
-		Foo string
-		Bar string
+		Street string `automapper:"BillingAddress/Street"`
 	}{}
-	MapFromSource(&source, &dest)
-	assert.Equal(t, source.Foo, dest.Foo)
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, "bar", dest.Street)
 }
 
-func TestMapSourceField_Panics(t *testing.T) {
-	defer func() { recover() }()
+func TestSelectFieldByAmbiguousTypePanics(t *testing.T) {
+	defer func() {
+		r := recover()
+		assert.Contains(t, r, "ambiguous field type 'BillingAddress'")
+	}()
 	source := struct {
-		Foo string
-	}{"abc"}
+		Street string `automapper:"BillingAddress/Street"`
+	}{"foo"}
 	dest := struct {
+		Home, Work BillingAddress
 	}{}
+
 	MapFromSource(&source, &dest)
 	t.Error("Should have panicked")
 }
 
-func TestMapSourceField_Skip(t *testing.T) {
+func TestSelectFieldWithDottedPathThroughNilPointer(t *testing.T) {
 	source := struct {
-		Foo string `automapper:"-"`
-	}{"abc"}
-	dest := struct {
-		Bar string
+		Child *SourceTypeA
 	}{}
-	MapFromSource(&source, &dest)
-	assert.Empty(t, dest.Bar)
+	dest := struct {
+		Foo int `automapper:"Child.Foo"`
+	}{42}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, 42, dest.Foo)
+
+	source.Child = &SourceTypeA{Foo: 1}
+	MapToDestination(&source, &dest)
+	assert.Equal(t, 1, dest.Foo)
 }
 
-func TestMapSourceField_FromAnonymous(t *testing.T) {
-	source := struct{
-		SourceTypeA
-	}{
-		SourceTypeA: SourceTypeA{Foo: 42},
-	}
-	dest := DestTypeA{}
-	MapFromSource(&source, &dest)
-	assert.Equal(t, source.Foo, dest.Foo)
+type Audit struct {
+	CreatedAt, UpdatedAt int
 }
 
-func TestMapSourceField_ToAnonymous(t *testing.T) {
-	source := SourceTypeA{Foo: 42}
-	dest := struct{
-		DestTypeA
-	}{}
-	MapFromSource(&source, &dest)
+type Entity struct {
+	ID int
+	Audit
+}
+
+type EntityRef struct {
+	ID int
+	*Audit
+}
+
+func TestMapPromotedFieldsFromTwoEmbeddingLevels(t *testing.T) {
+	source := struct {
+		Name string
+		Entity
+	}{"foo", Entity{ID: 1, Audit: Audit{CreatedAt: 2, UpdatedAt: 3}}}
+	dest := struct {
+		Name                     string
+		ID, CreatedAt, UpdatedAt int
+	}{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, "foo", dest.Name)
+	assert.Equal(t, 1, dest.ID)
+	assert.Equal(t, 2, dest.CreatedAt)
+	assert.Equal(t, 3, dest.UpdatedAt)
+}
+
+func TestMapPromotedFieldsThroughTwoEmbeddedPointers(t *testing.T) {
+	source := struct{ *EntityRef }{&EntityRef{ID: 1, Audit: &Audit{CreatedAt: 2}}}
+	dest := struct{ ID, CreatedAt int }{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, 1, dest.ID)
+	assert.Equal(t, 2, dest.CreatedAt)
+}
+
+func TestMapPromotedFieldsThroughNilInnerEmbeddedPointer(t *testing.T) {
+	source := struct{ *EntityRef }{&EntityRef{ID: 1}}
+	dest := struct{ ID, CreatedAt int }{0, 42}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, 1, dest.ID)
+	assert.Equal(t, 42, dest.CreatedAt)
+}
+
+func TestMapPromotedFieldsOfTwoEmbeddingLevelsFromSource(t *testing.T) {
+	source := struct {
+		Name string
+		Entity
+	}{"foo", Entity{ID: 1, Audit: Audit{CreatedAt: 2, UpdatedAt: 3}}}
+	dest := struct {
+		Name string
+		Entity
+	}{}
+
+	MapFromSource(&source, &dest)
+	assert.Equal(t, source.Entity, dest.Entity)
+}
+
+func TestAmbiguousEmbeddedFieldsPanics(t *testing.T) {
+	defer func() {
+		r := recover()
+		assert.Contains(t, r, "ambiguous source field 'Foo'; found in [SourceTypeA, DestTypeA]")
+	}()
+	source := struct {
+		SourceTypeA
+		DestTypeA
+	}{}
+	dest := struct {
+		Foo int
+	}{}
+
+	MapToDestination(&source, &dest)
+	t.Error("Should have panicked")
+}
+
+func TestWithUnnamedFields(t *testing.T) {
+	source := struct {
+		Baz string
+		SourceTypeA
+	}{}
+	dest := struct {
+		Baz string
+		DestTypeA
+	}{}
+	source.Baz = "Baz"
+	source.SourceTypeA.Foo = 42
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, "Baz", dest.Baz)
+	assert.Equal(t, 42, dest.DestTypeA.Foo)
+}
+
+func TestWithPointerFieldsNotNil(t *testing.T) {
+	source := struct {
+		Foo *SourceTypeA
+	}{}
+	dest := struct {
+		Foo *DestTypeA
+	}{}
+	source.Foo = nil
+
+	MapToDestination(&source, &dest)
+	assert.Nil(t, dest.Foo)
+}
+
+func TestWithPointerFieldsNil(t *testing.T) {
+	source := struct {
+		Foo *SourceTypeA
+	}{}
+	dest := struct {
+		Foo *DestTypeA
+	}{}
+	source.Foo = &SourceTypeA{Foo: 42}
+
+	MapToDestination(&source, &dest)
+	assert.NotNil(t, dest.Foo)
+	assert.Equal(t, 42, dest.Foo.Foo)
+}
+
+func TestWithPointersToConvertibleTypes(t *testing.T) {
+	type SourceName string
+	type DestName string
+	foo, bar := 42, SourceName("Bar")
+	source := struct {
+		Foo *int
+		Bar *SourceName
+		Baz *int
+	}{&foo, &bar, &foo}
+	dest := struct {
+		Foo *int64
+		Bar *DestName
+		Baz *float64
+	}{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, int64(42), *dest.Foo)
+	assert.Equal(t, DestName("Bar"), *dest.Bar)
+	assert.Equal(t, float64(42), *dest.Baz)
+}
+
+func TestWithNilPointersToConvertibleTypes(t *testing.T) {
+	type SourceName string
+	type DestName string
+	source := struct {
+		Foo *int
+		Bar *SourceName
+	}{}
+	foo, bar := int64(1), DestName("Bar")
+	dest := struct {
+		Foo *int64
+		Bar *DestName
+	}{&foo, &bar}
+
+	MapToDestination(&source, &dest)
+	assert.Nil(t, dest.Foo)
+	assert.Nil(t, dest.Bar)
+}
+
+func TestMapToDestinationPointerToNonPointerTypeWithData(t *testing.T) {
+	source := struct {
+		Foo *SourceTypeA
+	}{}
+	dest := struct {
+		Foo DestTypeA
+	}{}
+	source.Foo = &SourceTypeA{Foo: 42}
+
+	MapToDestination(&source, &dest)
+	assert.NotNil(t, dest.Foo)
+	assert.Equal(t, 42, dest.Foo.Foo)
+}
+
+func TestMapToDestinationPointerToNonPointerTypeWithoutData(t *testing.T) {
+	source := struct {
+		Foo *SourceTypeA
+	}{}
+	dest := struct {
+		Foo DestTypeA
+	}{}
+	source.Foo = nil
+
+	MapToDestination(&source, &dest)
+	assert.NotNil(t, dest.Foo)
+	assert.Equal(t, 0, dest.Foo.Foo)
+}
+
+func TestMapToDestinationPointerToAnonymousTypeToFieldName(t *testing.T) {
+	source := struct {
+		*SourceTypeA
+	}{}
+	dest := struct {
+		Foo int
+	}{}
+	source.SourceTypeA = nil
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, 0, dest.Foo)
+}
+
+func TestMapToEmbeddedPointerInDestination(t *testing.T) {
+	source := struct {
+		Foo int
+		Bar string
+	}{42, "Bar"}
+	dest := struct{ *DestTypeA }{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, &DestTypeA{Foo: 42, Bar: "Bar"}, dest.DestTypeA)
+
+	dest.DestTypeA = nil
+	MapFromSource(&source, &dest)
+	assert.Equal(t, &DestTypeA{Foo: 42, Bar: "Bar"}, dest.DestTypeA)
+}
+
+func TestMapFromEmbeddedPointerInSource(t *testing.T) {
+	source := struct{ *SourceTypeA }{&SourceTypeA{Foo: 42, Bar: "Bar"}}
+	dest := struct {
+		Foo int
+		Bar string
+	}{}
+
+	MapFromSource(&source, &dest)
+	assert.Equal(t, 42, dest.Foo)
+
+	source.SourceTypeA = nil
+	MapFromSource(&source, &dest)
+	assert.Equal(t, 42, dest.Foo, "nil embedded pointer should leave dest unchanged")
+}
+
+func TestMapBetweenEmbeddedPointers(t *testing.T) {
+	source := struct{ *SourceTypeA }{&SourceTypeA{Foo: 42, Bar: "Bar"}}
+	dest := struct{ *DestTypeA }{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, &DestTypeA{Foo: 42, Bar: "Bar"}, dest.DestTypeA)
+
+	dest.DestTypeA = nil
+	MapFromSource(&source, &dest)
+	assert.Equal(t, &DestTypeA{Foo: 42, Bar: "Bar"}, dest.DestTypeA)
+}
+
+func TestMapBetweenNilEmbeddedPointersLeavesDestinationNil(t *testing.T) {
+	source := struct{ *SourceTypeA }{}
+	dest := struct{ *DestTypeA }{}
+
+	MapToDestination(&source, &dest)
+	assert.Nil(t, dest.DestTypeA)
+
+	MapFromSource(&source, &dest)
+	assert.Nil(t, dest.DestTypeA)
+}
+
+func TestMapToDestinationNonNilPointerToAnonymousTypeToFieldName(t *testing.T) {
+	source := struct {
+		*SourceTypeA
+	}{&SourceTypeA{Foo: 42, Bar: "Bar"}}
+	dest := struct {
+		Foo int
+		Bar string
+	}{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, 42, dest.Foo)
+	assert.Equal(t, "Bar", dest.Bar)
+}
+
+func TestMapToDestinationNonNilPointerToAnonymousTypeToAnonymousType(t *testing.T) {
+	source := struct {
+		*SourceTypeA
+	}{&SourceTypeA{Foo: 42, Bar: "Bar"}}
+	dest := struct {
+		DestTypeA
+	}{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, 42, dest.Foo)
+	assert.Equal(t, "Bar", dest.Bar)
+}
+
+func TestMapToDestinationTwoLevelPointerToAnonymousTypeToFieldName(t *testing.T) {
+	type Outer struct {
+		*SourceTypeA
+	}
+	source := struct {
+		*Outer
+	}{&Outer{&SourceTypeA{Foo: 42}}}
+	dest := struct {
+		Foo int
+	}{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, 42, dest.Foo)
+
+	source.Outer.SourceTypeA = nil
+	dest.Foo = 1
+	MapToDestination(&source, &dest)
+	assert.Equal(t, 1, dest.Foo)
+
+	source.Outer = nil
+	MapToDestination(&source, &dest)
+	assert.Equal(t, 1, dest.Foo)
+}
+
+func TestMapToDestinationPointerToAnonymousTypeInNamedField(t *testing.T) {
+	source := struct {
+		Child struct {
+			*SourceTypeA
+		}
+	}{}
+	dest := struct {
+		Foo int
+	}{1}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, 1, dest.Foo)
+
+	source.Child.SourceTypeA = &SourceTypeA{Foo: 42}
+	MapToDestination(&source, &dest)
+	assert.Equal(t, 42, dest.Foo)
+}
+
+func TestMapFromSourceNonNilPointerToAnonymousType(t *testing.T) {
+	source := struct {
+		*SourceTypeA
+	}{&SourceTypeA{Foo: 42}}
+	dest := struct {
+		Foo int
+		Bar string
+	}{}
+
+	MapFromSource(&source, &dest)
+	assert.Equal(t, 42, dest.Foo)
+}
+
+func TestMapToDestinationPointerToNonPointerTypeWithoutDataAndIncompatibleType(t *testing.T) {
+	defer func() { recover() }()
+	// Just make sure we stil panic
+	source := struct {
+		Foo *SourceTypeA
+	}{}
+	dest := struct {
+		Foo struct {
+			Baz string
+		}
+	}{}
+	source.Foo = nil
+
+	MapToDestination(&source, &dest)
+	t.Error("Should have panicked")
+}
+
+func TestWhenUsingIncompatibleTypes(t *testing.T) {
+	defer func() { recover() }()
+	source := struct{ Foo string }{}
+	dest := struct{ Foo int }{}
+	MapToDestination(&source, &dest)
+	t.Error("Should have panicked")
+}
+
+func TestSetStructOfSameTypeDirectly(t *testing.T) {
+	type FooType struct {
+		time.Time
+	}
+	source := struct {
+		Foo FooType
+	}{FooType{Time: time.Now().UTC()}}
+	dest := struct {
+		Foo FooType
+	}{}
+	MapToDestination(&source, &dest)
+	assert.Equal(t, source.Foo.String(), dest.Foo.String())
+}
+
+func TestNamedType(t *testing.T) {
+	type SourceType string
+	type DestType string
+	source := struct {
+		Foo SourceType
+	}{"abc"}
+	dest := struct {
+		Foo DestType
+	}{}
+	MapToDestination(&source, &dest)
+	assert.Equal(t, string(source.Foo), string(dest.Foo))
+}
+
+func TestSkip(t *testing.T) {
+	source := struct {
+		Foo string
+	}{"abc"}
+	dest := struct {
+		Foo string
+		Bar string `automapper:"-"`
+	}{}
+	MapToDestination(&source, &dest)
+	assert.Empty(t, dest.Bar)
+}
+
+func TestMapToDestination(t *testing.T) {
+	source := struct {
+		Foo string
+	}{"abc"}
+	dest := struct {
+		Bar string `automapper:"Foo"`
+	}{}
+	MapToDestination(&source, &dest)
+	assert.Equal(t, source.Foo, dest.Bar)
+}
+
+func TestMapFromSource(t *testing.T) {
+	source := struct {
+		Foo string `automapper:"Bar"`
+	}{"abc"}
+	dest := struct {
+		Bar string
+	}{}
+	MapFromSource(&source, &dest)
+	assert.Equal(t, source.Foo, dest.Bar)
+}
+
+func TestMapSourceField_DestContainsUnmappedFields(t *testing.T) {
+	source := struct {
+		Foo string
+	}{"abc"}
+	dest := struct {
+		Foo string
+		Bar string
+	}{}
+	MapFromSource(&source, &dest)
 	assert.Equal(t, source.Foo, dest.Foo)
 }
 
-func TestMapSourceField_BothAnonymous(t *testing.T) {
+func TestMapSourceField_Panics(t *testing.T) {
+	defer func() { recover() }()
+	source := struct {
+		Foo string
+	}{"abc"}
+	dest := struct {
+	}{}
+	MapFromSource(&source, &dest)
+	t.Error("Should have panicked")
+}
+
+func TestMapSourceField_Skip(t *testing.T) {
+	source := struct {
+		Foo string `automapper:"-"`
+	}{"abc"}
+	dest := struct {
+		Bar string
+	}{}
+	MapFromSource(&source, &dest)
+	assert.Empty(t, dest.Bar)
+}
+
+func TestMapSourceField_FromAnonymous(t *testing.T) {
+	source := struct{
+		SourceTypeA
+	}{
+		SourceTypeA: SourceTypeA{Foo: 42},
+	}
+	dest := DestTypeA{}
+	MapFromSource(&source, &dest)
+	assert.Equal(t, source.Foo, dest.Foo)
+}
+
+func TestMapSourceField_ToAnonymous(t *testing.T) {
+	source := SourceTypeA{Foo: 42}
+	dest := struct{
+		DestTypeA
+	}{}
+	MapFromSource(&source, &dest)
+	assert.Equal(t, source.Foo, dest.Foo)
+}
+
+func TestMapSourceField_BothAnonymous(t *testing.T) {
+	source := struct {
+		SourceTypeA
+	}{
+		SourceTypeA: SourceTypeA{Foo: 42},
+	}
+	dest := struct {
+		DestTypeA
+	}{}
+
+	MapFromSource(&source, &dest)
+	assert.Equal(t, source.Foo, dest.Foo)
+}
+
+func TestMapFromSourceMap(t *testing.T) {
+	type childSrc struct {
+		Foo string
+	}
+	type childDest struct {
+		Foo string
+		Bar string
+	}
+	source := map[string]interface{}{
+		"Foo": "abc",
+		"Child": childSrc{Foo: "456"},
+	}
+	dest := struct {
+		Foo string
+		Bar string
+		Child childDest
+	}{Bar: "123"}
+
+	MapFromSourceMap(source, &dest)
+
+	assert.Equal(t, "abc", dest.Foo, "should map direct field")
+	assert.Equal(t, "123", dest.Bar, "field should not be overwritten")
+	assert.Equal(t, "456", dest.Child.Foo, "struct fields should be mapped")
+}
+
+func TestMapFromSourceMapWithSliceOfMaps(t *testing.T) {
+	type item struct {
+		Name  string
+		Price float64
+	}
+	source := map[string]interface{}{
+		"Owner": map[string]interface{}{"Name": "foo"},
+		"Items": []interface{}{
+			map[string]interface{}{"Name": "a", "Price": 1.5},
+			map[string]interface{}{"Name": "b", "Price": 2.0},
+		},
+	}
+	dest := struct {
+		Owner struct{ Name string }
+		Items []item
+	}{}
+
+	MapFromSourceMap(source, &dest)
+
+	assert.Equal(t, "foo", dest.Owner.Name)
+	assert.Equal(t, []item{{"a", 1.5}, {"b", 2.0}}, dest.Items)
+}
+
+func TestMapFromSourceMapWithUnknownKeyPanics(t *testing.T) {
+	defer func() {
+		r := recover()
+		assert.Contains(t, r, "no destination field 'Baz'")
+	}()
+	source := map[string]interface{}{
+		"Items": []interface{}{map[string]interface{}{"Baz": 1}},
+	}
+	dest := struct{ Items []struct{ Name string } }{}
+
+	MapFromSourceMap(source, &dest)
+	t.Error("Should have panicked")
+}
+
+func TestMapArrayToSlice(t *testing.T) {
+	source := struct{ Items [2]SourceTypeA }{[2]SourceTypeA{{Foo: 1}, {Foo: 2}}}
+	dest := struct{ Items []DestTypeA }{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, []DestTypeA{{Foo: 1}, {Foo: 2}}, dest.Items)
+}
+
+func TestMapArrayOfPointersToSlice(t *testing.T) {
+	source := struct {
+		Items  [3]*SourceTypeA
+		Values [3]*SourceTypeA
+	}{
+		Items:  [3]*SourceTypeA{{Foo: 1}, nil, {Foo: 3}},
+		Values: [3]*SourceTypeA{{Foo: 1}, nil, {Foo: 3}},
+	}
+	dest := struct {
+		Items  []*DestTypeA
+		Values []DestTypeA
+	}{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, []*DestTypeA{{Foo: 1}, nil, {Foo: 3}}, dest.Items)
+	assert.Equal(t, []DestTypeA{{Foo: 1}, {}, {Foo: 3}}, dest.Values)
+}
+
+func TestMapArrayOfScalarsToSlice(t *testing.T) {
+	source := struct {
+		Ints  [3]int
+		Empty [0]int
+	}{Ints: [3]int{1, 2, 3}}
+	dest := struct {
+		Ints  []int64
+		Empty []int64
+	}{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, []int64{1, 2, 3}, dest.Ints)
+	assert.Equal(t, []int64{}, dest.Empty)
+}
+
+func TestMapSliceToArray(t *testing.T) {
+	source := struct{ Items []SourceTypeA }{[]SourceTypeA{{Foo: 1}, {Foo: 2}}}
+	dest := struct{ Items [2]DestTypeA }{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, [2]DestTypeA{{Foo: 1}, {Foo: 2}}, dest.Items)
+}
+
+func TestMapArrayToArrayOfOtherElementType(t *testing.T) {
+	source := struct{ Items [3]int32 }{[3]int32{1, 2, 3}}
+	dest := struct{ Items [3]int64 }{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, [3]int64{1, 2, 3}, dest.Items)
+}
+
+func TestMapSliceToArrayOfOtherLengthPanics(t *testing.T) {
+	defer func() {
+		r := recover()
+		assert.Contains(t, r, "cannot map 3 elements to [2]int")
+	}()
+	source := struct{ Items []int }{[]int{1, 2, 3}}
+	dest := struct{ Items [2]int }{}
+
+	MapToDestination(&source, &dest)
+	t.Error("Should have panicked")
+}
+
+type namedShape struct {
+	Name     string
+	Tags     []string
+	Children []SourceTypeA
+	Parent   *SourceTypeA
+}
+
+func TestMapAnonymousStructToNamedStruct(t *testing.T) {
+	source := struct {
+		Name     string
+		Tags     []string
+		Children []SourceTypeA
+		Parent   *SourceTypeA
+	}{"foo", []string{"a"}, []SourceTypeA{{Foo: 1}}, &SourceTypeA{Foo: 2}}
+	dest := namedShape{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, namedShape{"foo", []string{"a"}, []SourceTypeA{{Foo: 1}}, &SourceTypeA{Foo: 2}}, dest)
+}
+
+func TestMapNamedStructToAnonymousStruct(t *testing.T) {
+	source := namedShape{"foo", []string{"a"}, []SourceTypeA{{Foo: 1}}, &SourceTypeA{Foo: 2}}
+	dest := struct {
+		Name     string
+		Tags     []string
+		Children []DestTypeA
+		Parent   *DestTypeA
+	}{}
+
+	MapFromSource(&source, &dest)
+	assert.Equal(t, "foo", dest.Name)
+	assert.Equal(t, []string{"a"}, dest.Tags)
+	assert.Equal(t, []DestTypeA{{Foo: 1}}, dest.Children)
+	assert.Equal(t, &DestTypeA{Foo: 2}, dest.Parent)
+}
+
+func TestMapNestedAnonymousStructsToNamedStructs(t *testing.T) {
+	type shape = struct {
+		Foo int
+		Bar string
+	}
+	source := struct {
+		Shape  shape
+		Shapes []shape
+		Ptr    *shape
+	}{shape{1, "a"}, []shape{{2, "b"}}, &shape{3, "c"}}
+	dest := struct {
+		Shape  DestTypeA
+		Shapes []DestTypeA
+		Ptr    *DestTypeA
+	}{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, DestTypeA{Foo: 1, Bar: "a"}, dest.Shape)
+	assert.Equal(t, []DestTypeA{{Foo: 2, Bar: "b"}}, dest.Shapes)
+	assert.Equal(t, &DestTypeA{Foo: 3, Bar: "c"}, dest.Ptr)
+
+	back := struct {
+		Shape  shape
+		Shapes []shape
+		Ptr    *shape
+	}{}
+	MapFromSource(&dest, &back)
+	assert.Equal(t, source, back)
+}
+
+func TestMapSliceOfPointersToSliceOfValues(t *testing.T) {
+	source := struct{ Items []*SourceTypeA }{[]*SourceTypeA{{Foo: 1}, nil}}
+	dest := struct{ Items []DestTypeA }{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, []DestTypeA{{Foo: 1}, {}}, dest.Items)
+}
+
+func TestMapSliceOfValuesToSliceOfPointers(t *testing.T) {
+	source := struct{ Items []SourceTypeA }{[]SourceTypeA{{Foo: 1}, {Foo: 2}}}
+	dest := struct{ Items []*DestTypeA }{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, []*DestTypeA{{Foo: 1}, {Foo: 2}}, dest.Items)
+}
+
+func TestMapSliceOfPointersToSliceOfPointers(t *testing.T) {
+	source := struct{ Items []*SourceTypeA }{[]*SourceTypeA{{Foo: 1}, nil}}
+	dest := struct{ Items []*DestTypeA }{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, []*DestTypeA{{Foo: 1}, nil}, dest.Items)
+}
+
+func TestMapSliceOfPointersToSliceOfScalars(t *testing.T) {
+	one := 1
+	source := struct{ Items []*int }{[]*int{&one, nil}}
+	dest := struct{ Items []int64 }{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, []int64{1, 0}, dest.Items)
+}
+
+func TestMapBytesToByteArray(t *testing.T) {
+	type Key [4]byte
+	source := struct {
+		Key, Hash, Nil []byte
+	}{Key: []byte{1, 2, 3, 4}, Hash: []byte{5, 6, 7, 8}}
+	dest := struct {
+		Key  Key
+		Hash [4]byte
+		Nil  [4]byte
+	}{Nil: [4]byte{1}}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, Key{1, 2, 3, 4}, dest.Key)
+	assert.Equal(t, [4]byte{5, 6, 7, 8}, dest.Hash)
+	assert.Equal(t, [4]byte{}, dest.Nil)
+}
+
+func TestMapBytesOfOtherLengthToByteArray(t *testing.T) {
+	for _, key := range [][]byte{{1, 2, 3}, {1, 2, 3, 4, 5}} {
+		source := struct{ Key []byte }{key}
+		dest := struct{ Key [4]byte }{}
+		err := NewMapper().MapDir(&source, &dest, ToDestination)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), fmt.Sprintf("cannot map %d bytes to [4]uint8", len(key)))
+	}
+}
+
+func TestMapBytesToByteArrayWithArrayResize(t *testing.T) {
+	source := struct{ Short, Long []byte }{[]byte{1, 2, 3}, []byte{1, 2, 3, 4, 5}}
+	dest := struct{ Short, Long [4]byte }{}
+
+	MapToDestination(&source, &dest, WithArrayResize())
+	assert.Equal(t, [4]byte{1, 2, 3, 0}, dest.Short)
+	assert.Equal(t, [4]byte{1, 2, 3, 4}, dest.Long)
+}
+
+func TestMapByteArrayToBytes(t *testing.T) {
+	source := struct{ Key [4]byte }{[4]byte{1, 2, 3, 4}}
+	dest := struct{ Key []byte }{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, []byte{1, 2, 3, 4}, dest.Key)
+	dest.Key[0] = 9
+	assert.Equal(t, byte(1), source.Key[0])
+}
+
+type deepAudit struct {
+	CreatedBy string
+}
+
+type deepTimestamps struct {
+	deepAudit
+	CreatedAt int
+}
+
+type deepEntity struct {
+	*deepTimestamps
+	ID int
+}
+
+type deepVersion struct {
+	Version   int
+	CreatedBy string
+}
+
+type deepIdentityDTO struct {
+	ID        int
+	CreatedBy string
+}
+
+type deepEntityDTO struct {
+	deepIdentityDTO
+	CreatedAt int
+}
+
+func TestMapDeeplyEmbeddedFieldsInDifferentOrder(t *testing.T) {
+	source := struct {
+		Name string
+		deepEntity
+	}{"foo", deepEntity{&deepTimestamps{deepAudit{"bob"}, 2}, 1}}
+	dest := struct {
+		deepEntityDTO
+		Name string
+	}{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, "foo", dest.Name)
+	assert.Equal(t, 1, dest.ID)
+	assert.Equal(t, 2, dest.CreatedAt)
+	assert.Equal(t, "bob", dest.CreatedBy)
+}
+
+func TestMapDeeplyEmbeddedFieldsShallowestWins(t *testing.T) {
+	source := struct {
+		deepEntity
+		deepVersion
+	}{deepEntity{&deepTimestamps{deepAudit{"deep"}, 2}, 1}, deepVersion{3, "shallow"}}
+	dest := struct {
+		CreatedBy string
+		Version   int
+	}{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, "shallow", dest.CreatedBy)
+	assert.Equal(t, 3, dest.Version)
+}
+
+func TestMapNilInterfaceSetsZeroValue(t *testing.T) {
 	source := struct {
+		Ptr    interface{}
+		Struct interface{}
+		Scalar interface{}
+	}{}
+	dest := struct {
+		Ptr    *DestTypeA
+		Struct DestTypeA
+		Scalar int
+	}{&DestTypeA{Foo: 1}, DestTypeA{Foo: 2}, 3}
+
+	MapToDestination(&source, &dest)
+	assert.Nil(t, dest.Ptr)
+	assert.Equal(t, DestTypeA{}, dest.Struct)
+	assert.Equal(t, 0, dest.Scalar)
+}
+
+func TestMapNilInterfaceFromSourceMap(t *testing.T) {
+	source := map[string]interface{}{"Foo": nil, "Bar": nil}
+	dest := struct {
+		Foo *int
+		Bar string
+	}{}
+
+	MapFromSourceMap(source, &dest)
+	assert.Nil(t, dest.Foo)
+	assert.Equal(t, "", dest.Bar)
+}
+
+func TestMapIntoDestinationEmbeddingSourceType(t *testing.T) {
+	source := SourceTypeA{Foo: 1, Bar: "bar"}
+	dest := struct {
 		SourceTypeA
-	}{
-		SourceTypeA: SourceTypeA{Foo: 42},
+		Extra string
+	}{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, source, dest.SourceTypeA)
+	assert.Equal(t, "", dest.Extra)
+}
+
+func TestMapIntoDestinationEmbeddingPointerToSourceType(t *testing.T) {
+	source := SourceTypeA{Foo: 1, Bar: "bar"}
+	dest := struct {
+		*SourceTypeA
+		Extra string
+	}{Extra: "extra"}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, &source, dest.SourceTypeA)
+	assert.NotSame(t, &source, dest.SourceTypeA)
+	assert.Equal(t, "extra", dest.Extra)
+}
+
+func TestMapIntoDestinationEmbeddingSourceTypeInSlice(t *testing.T) {
+	type Decorated struct {
+		SourceTypeA
+		Extra string
 	}
+	source := []SourceTypeA{{Foo: 1}, {Foo: 2}}
+	var dest []Decorated
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, []Decorated{{SourceTypeA: SourceTypeA{Foo: 1}}, {SourceTypeA: SourceTypeA{Foo: 2}}}, dest)
+}
+
+func TestMapIntoDestinationEmbeddingSourceTypeUsesAllSourceFields(t *testing.T) {
+	source := SourceTypeA{Foo: 1, Bar: "bar"}
 	dest := struct {
-		DestTypeA
+		SourceTypeA
+		Extra string
 	}{}
 
-	MapFromSource(&source, &dest)
-	assert.Equal(t, source.Foo, dest.Foo)
+	err := NewMapper(WithStrict()).MapDir(&source, &dest, ToDestination)
+	assert.NoError(t, err)
+	assert.Equal(t, source, dest.SourceTypeA)
 }
 
-func TestMapFromSourceMap(t *testing.T) {
-	type childSrc struct {
+func TestMapIntoDestinationEmbeddingSourceTypeMapsShadowingFields(t *testing.T) {
+	source := SourceTypeA{Foo: 1, Bar: "bar"}
+	dest := struct {
+		SourceTypeA
+		Bar   string
+		Extra string
+	}{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, source, dest.SourceTypeA)
+	assert.Equal(t, "bar", dest.Bar)
+	assert.Equal(t, "", dest.Extra)
+}
+
+func TestMapIntoDestinationEmbeddingSourceTypeRequiresNestedSourceFields(t *testing.T) {
+	source := SourceTypeA{Foo: 1}
+	dest := struct {
+		SourceTypeA
+		Nested struct{ Missing int }
+	}{}
+
+	assert.Panics(t, func() { MapToDestination(&source, &dest) })
+}
+
+func TestMapStringToBytes(t *testing.T) {
+	source := struct {
 		Foo string
-	}
-	type childDest struct {
+	}{"abc"}
+	dest := struct {
+		Foo []byte
+	}{}
+	MapToDestination(&source, &dest)
+	assert.Equal(t, []byte("abc"), dest.Foo)
+}
+
+func TestMapBytesToString(t *testing.T) {
+	source := struct {
+		Foo []byte
+	}{[]byte("abc")}
+	dest := struct {
+		Foo string
+	}{}
+	MapToDestination(&source, &dest)
+	assert.Equal(t, "abc", dest.Foo)
+
+	source.Foo[0] = 'x'
+	assert.Equal(t, "abc", dest.Foo)
+}
+
+func TestMapEmptyStringAndBytes(t *testing.T) {
+	source := struct {
 		Foo string
+		Bar []byte
+	}{}
+	dest := struct {
+		Foo []byte
 		Bar string
+	}{}
+	MapToDestination(&source, &dest)
+	assert.Len(t, dest.Foo, 0)
+	assert.Equal(t, "", dest.Bar)
+}
+
+type Payload interface{}
+
+func TestMapSourceField_FromEmbeddedInterface(t *testing.T) {
+	source := struct {
+		Payload
+	}{
+		Payload: SourceTypeA{Foo: 42, Bar: "Bar"},
 	}
-	source := map[string]interface{}{
-		"Foo": "abc",
-		"Child": childSrc{Foo: "456"},
+	dest := DestTypeA{}
+	MapFromSource(&source, &dest)
+	assert.Equal(t, 42, dest.Foo)
+	assert.Equal(t, "Bar", dest.Bar)
+}
+
+func TestMapSourceField_FromEmbeddedInterfaceHoldingPointer(t *testing.T) {
+	source := struct {
+		Payload
+	}{
+		Payload: &SourceTypeA{Foo: 42},
 	}
-	dest := struct {
-		Foo string
+	dest := DestTypeA{}
+	MapFromSource(&source, &dest)
+	assert.Equal(t, 42, dest.Foo)
+}
+
+func TestMapSourceField_FromNilEmbeddedInterface(t *testing.T) {
+	source := struct {
+		Payload
+	}{}
+	dest := DestTypeA{Foo: 42}
+	MapFromSource(&source, &dest)
+	assert.Equal(t, 42, dest.Foo)
+}
+
+func TestMapDestField_FromNilEmbeddedInterface(t *testing.T) {
+	source := struct {
+		Payload
 		Bar string
-		Child childDest
-	}{Bar: "123"}
+	}{Bar: "Bar"}
+	dest := DestTypeA{Foo: 42}
+	MapToDestination(&source, &dest)
+	assert.Equal(t, 42, dest.Foo)
+	assert.Equal(t, "Bar", dest.Bar)
+}
 
-	MapFromSourceMap(source, &dest)
+func TestMapDestField_FromEmbeddedInterface(t *testing.T) {
+	source := struct {
+		Payload
+	}{
+		Payload: SourceTypeA{Foo: 42, Bar: "Bar"},
+	}
+	dest := DestTypeA{}
+	MapToDestination(&source, &dest)
+	assert.Equal(t, 42, dest.Foo)
+	assert.Equal(t, "Bar", dest.Bar)
+}
 
-	assert.Equal(t, "abc", dest.Foo, "should map direct field")
-	assert.Equal(t, "123", dest.Bar, "field should not be overwritten")
-	assert.Equal(t, "456", dest.Child.Foo, "struct fields should be mapped")
+type describer interface {
+	Describe() string
+}
+
+type namedDescriber struct{ Name string }
+
+func (d *namedDescriber) Describe() string { return d.Name }
+
+func TestMapErrorFieldsByAssignment(t *testing.T) {
+	cause := &os.PathError{Op: "open", Path: "foo", Err: os.ErrNotExist}
+	source := struct{ Err error }{cause}
+	dest := struct {
+		Err error `automapper:"Err"`
+	}{}
+
+	MapToDestination(&source, &dest)
+	assert.True(t, dest.Err == error(cause))
+}
+
+func TestMapCustomInterfaceFieldsByAssignment(t *testing.T) {
+	value := &namedDescriber{"foo"}
+	source := struct{ Value describer }{value}
+	dest := struct {
+		Value describer `automapper:"Value"`
+	}{}
+
+	MapFromSource(&source, &dest)
+	assert.True(t, dest.Value.(*namedDescriber) == value)
+
+	source.Value = nil
+	MapFromSource(&source, &dest)
+	assert.Nil(t, dest.Value)
+}
+
+func TestMapEmptyInterfaceFieldsByAssignment(t *testing.T) {
+	value := &SourceTypeA{Foo: 42}
+	source := struct{ Value interface{} }{value}
+	dest := struct {
+		Value interface{} `automapper:"Value"`
+	}{}
+
+	MapToDestination(&source, &dest)
+	assert.True(t, dest.Value.(*SourceTypeA) == value)
+}
+
+func TestMapInterfaceFieldToEmptyInterfaceByAssignment(t *testing.T) {
+	value := &namedDescriber{"foo"}
+	source := struct{ Value describer }{value}
+	dest := struct{ Value interface{} }{}
+
+	MapToDestination(&source, &dest)
+	assert.True(t, dest.Value.(*namedDescriber) == value)
 }
 
 type SourceParent struct {
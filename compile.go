@@ -0,0 +1,540 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// CompileMapper prepares the mapping of values of sourceType to values of
+// destType, like MapToDestination would do it, and returns a function
+// performing it. The types are checked for compatibility once, so an error is
+// returned here instead of when mapping. Both types must be structs.
+//
+// The check follows pointers, slices and maps to the types of their elements,
+// without calling any of the functions passed as options. Values held by
+// interfaces, and values supplied by a resolver, can only be checked when
+// mapping.
+//
+// The returned function takes a value of sourceType or a pointer to one, and
+// a pointer to a value of destType. It panics if it is called with values of
+// other types, or if mapping the values fails, e.g. because a converter
+// returns an error.
+//
+// The error returned for types that can't be mapped is an
+// *IncompatibleTypesError.
+func CompileMapper(sourceType, destType reflect.Type, opts ...Option) (func(source, dest interface{}), error) {
+	sourceType, destType = derefType(sourceType), derefType(destType)
+	if sourceType.Kind() != reflect.Struct || destType.Kind() != reflect.Struct {
+		return nil, &IncompatibleTypesError{SourceType: sourceType, DestType: destType, Err: errors.New("both types must be structs")}
+	}
+	options := newMapOptions(false, opts)
+	if err := verifyCompatibleTypes(sourceType, destType, options); err != nil {
+		return nil, err
+	}
+	var plan *compiledPlan
+	if options.compilesFields() && options.mapsFieldsOf(sourceType, destType) {
+		plan = compilePlan(sourceType, destType, options, map[typePair]*compiledPlan{})
+	}
+	return func(source, dest interface{}) {
+		sourceVal := reflect.Indirect(reflect.ValueOf(source))
+		if !sourceVal.IsValid() || sourceVal.Type() != sourceType {
+			panic(fmt.Sprintf("compiled mapper expects a source of type %v, got %T", sourceType, source))
+		}
+		destVal := reflect.ValueOf(dest)
+		if destVal.Type() != reflect.PtrTo(destType) || destVal.IsNil() {
+			panic(fmt.Sprintf("compiled mapper expects a non-nil dest of type *%v, got %T", destType, dest))
+		}
+		if plan == nil {
+			mapRootValues(sourceVal, destVal.Elem(), options)
+			return
+		}
+		opts := options
+		opts.state = newMapState(opts)
+		plan.run(sourceVal, destVal.Elem(), opts)
+		verifyRequiredFields(destVal.Elem(), opts)
+	}, nil
+}
+
+// MustCompileMapper works like CompileMapper, but panics if the types can't be
+// mapped. It is meant for initializing package level variables:
+//
+//	var mapUser = automapper.MustCompileMapper(reflect.TypeOf(User{}), reflect.TypeOf(UserDTO{}))
+func MustCompileMapper(sourceType, destType reflect.Type, opts ...Option) func(source, dest interface{}) {
+	mapper, err := CompileMapper(sourceType, destType, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return mapper
+}
+
+// IncompatibleTypesError is returned by CompileMapper for types that can't be
+// mapped. MapSliceInto panics with it for a source that is not a slice.
+type IncompatibleTypesError struct {
+	SourceType, DestType reflect.Type
+	// Path is the dotted path of the destination field that can't be
+	// mapped. It is empty if the types can't be mapped as a whole.
+	Path string
+	// Err is the error the mapping failed with.
+	Err error
+}
+
+func (e *IncompatibleTypesError) Error() string {
+	return fmt.Sprintf("cannot map %v to %v: %v", e.SourceType, e.DestType, e.Err)
+}
+
+func (e *IncompatibleTypesError) Unwrap() error {
+	return e.Err
+}
+
+// compiledPlan holds the steps that map all fields of a struct type to
+// another, with the fields and converters looked up in advance.
+type compiledPlan struct {
+	sourceType, destType reflect.Type
+	steps                []compiledStep
+}
+
+// stepKind tells how a compiledStep maps its field.
+type stepKind int
+
+const (
+	// stepField maps the field like mapDestField does, for fields that
+	// depend on tags, values or options.
+	stepField stepKind = iota
+	// stepDirect copies a field of the same scalar type.
+	stepDirect
+	// stepConvert converts the field with the converter of the step.
+	stepConvert
+	// stepNested maps a struct field with the nested plan of the step.
+	stepNested
+	// stepValue maps the field with mapValues.
+	stepValue
+)
+
+// compiledStep maps the destination field at destIndex. Except for stepField,
+// the source field is found at sourceIndex.
+type compiledStep struct {
+	kind        stepKind
+	destIndex   int
+	destName    string
+	sourceIndex []int
+	sourcePath  string
+	conv        converter
+	nested      *compiledPlan
+}
+
+// compilesFields returns true if the options allow mapping fields with a
+// compiledPlan. Options that change how fields are matched, or that track the
+// fields being mapped, need the full mapping of every field.
+func (o mapOptions) compilesFields() bool {
+	return !o.tagMatching && len(o.fieldTransforms) == 0 && len(o.aliases) == 0 && o.ignorePattern == nil &&
+		o.mask == nil && o.resolver == nil && o.tagParser == nil && !o.verifiesFields() && !o.positionalMatch &&
+		!o.useSourceMemberList && !o.unsafeUnexported && !o.tracesValues()
+}
+
+// mapsFieldsOf returns true if mapValues maps values of the two types with
+// mapFields, matching the fields by name.
+func (o mapOptions) mapsFieldsOf(sourceType, destType reflect.Type) bool {
+	if sourceType == destType || sourceType.Kind() != reflect.Struct || destType.Kind() != reflect.Struct {
+		return false
+	}
+	if _, hasConverter := o.converter(sourceType, destType); hasConverter {
+		return false
+	}
+	if o.sqlValueTypes && (isScannerPair(sourceType, destType) || isValuerPair(sourceType, destType)) {
+		return false
+	}
+	return !o.embedsSourceType(sourceType, destType)
+}
+
+// compilePlan returns the plan mapping the fields of sourceType to destType.
+// plans holds the plans compiled so far, so recursive types share them.
+func compilePlan(sourceType, destType reflect.Type, opts mapOptions, plans map[typePair]*compiledPlan) *compiledPlan {
+	pair := typePair{sourceType, destType}
+	if plan, ok := plans[pair]; ok {
+		return plan
+	}
+	plan := &compiledPlan{sourceType: sourceType, destType: destType}
+	plans[pair] = plan
+	for i := 0; i < destType.NumField(); i++ {
+		plan.steps = append(plan.steps, compileStep(sourceType, destType.Field(i), i, opts, plans))
+	}
+	return plan
+}
+
+func compileStep(sourceType reflect.Type, destField reflect.StructField, i int, opts mapOptions, plans map[typePair]*compiledPlan) compiledStep {
+	step := compiledStep{destIndex: i, destName: destField.Name}
+	tag := opts.parseTag(destField)
+	if destField.PkgPath != "" || destField.Anonymous || tag.skip || tag.hasOptions || isPath(tag.name) || strings.Contains(tag.name, "|") {
+		return step
+	}
+	sourceField, ok := sourceType.FieldByName(tag.name)
+	if !ok || sourceField.PkgPath != "" || isPromotedThroughPointer(sourceType, sourceField.Index) {
+		return step
+	}
+	step.sourceIndex = sourceField.Index
+	step.sourcePath = fieldPath("", sourceType, tag.name)
+	if conv, ok := opts.converter(sourceField.Type, destField.Type); ok {
+		step.kind, step.conv = stepConvert, conv
+	} else if sourceField.Type == destField.Type && isScalarKind(destField.Type.Kind()) {
+		step.kind = stepDirect
+	} else if opts.mapsFieldsOf(sourceField.Type, destField.Type) {
+		step.kind, step.nested = stepNested, compilePlan(sourceField.Type, destField.Type, opts, plans)
+	} else {
+		step.kind = stepValue
+	}
+	return step
+}
+
+// isPromotedThroughPointer returns true if the field of t at index is
+// promoted through an embedded pointer, which may be nil.
+func isPromotedThroughPointer(t reflect.Type, index []int) bool {
+	for _, i := range index[:len(index)-1] {
+		t = t.Field(i).Type
+		if t.Kind() == reflect.Ptr {
+			return true
+		}
+	}
+	return false
+}
+
+// run maps the fields of sourceVal into destVal like mapFields does.
+func (p *compiledPlan) run(sourceVal, destVal reflect.Value, opts mapOptions) {
+	checkContext(opts)
+	for i := range p.steps {
+		switch step := &p.steps[i]; step.kind {
+		case stepDirect:
+			destVal.Field(step.destIndex).Set(sourceVal.FieldByIndex(step.sourceIndex))
+		case stepField:
+			mapDestField(sourceVal, destVal, step.destIndex, opts)
+		default:
+			p.runStep(step, sourceVal, destVal, opts)
+		}
+	}
+	validate(destVal, opts)
+}
+
+func (p *compiledPlan) runStep(step *compiledStep, sourceVal, destVal reflect.Value, opts mapOptions) {
+	opts.sourcePath = joinPath(opts.sourcePath, step.sourcePath)
+	opts.destPath = joinPath(opts.destPath, step.destName)
+	defer func() {
+		if r := recover(); r != nil {
+			opts.recordFailure(opts.destPath)
+			panicWithFieldContext(step.destName, p.destType, p.sourceType, r)
+		}
+	}()
+	sourceField, destField := sourceVal.FieldByIndex(step.sourceIndex), destVal.Field(step.destIndex)
+	switch step.kind {
+	case stepConvert:
+		convertLeaf(sourceField, destField, opts, step.conv)
+	case stepNested:
+		step.nested.run(sourceField, destField, opts)
+	default:
+		mapValues(sourceField, destField, opts)
+	}
+}
+
+// verifyCompatibleTypes verifies that values of sourceType can be mapped to
+// destType, following the fields and elements of the two types like the
+// mapping would. Nothing is mapped, so none of the functions passed as options
+// are called. Where the mapping depends on the values, e.g. for interfaces,
+// the types are assumed to be compatible.
+func verifyCompatibleTypes(sourceType, destType reflect.Type, opts mapOptions) (err error) {
+	var failedPath string
+	opts.failedPath = &failedPath
+	opts.state = newMapState(opts)
+	defer func() {
+		if r := recover(); r != nil {
+			err = &IncompatibleTypesError{SourceType: sourceType, DestType: destType, Path: failedPath, Err: errorFromPanic(r)}
+		}
+	}()
+	c := typeChecker{checked: map[typePair]bool{}}
+	c.check(sourceType, destType, opts)
+	if opts.verifiesFields() {
+		verifyAllFieldsMapped(sourceType, destType, opts)
+	}
+	for _, path := range opts.requiredFields {
+		if _, _, ok := lookupFieldType(destType, path); !ok {
+			panic(fmt.Sprintf("no destination field '%s'", path))
+		}
+	}
+	return nil
+}
+
+// typeChecker is the type level equivalent of mapValues, which panics like it
+// would for values of types that can't be mapped.
+type typeChecker struct {
+	// checked holds the struct types whose fields were checked, so every
+	// pair is checked once, and recursive types terminate.
+	checked map[typePair]bool
+}
+
+func (c typeChecker) check(sourceType, destType reflect.Type, opts mapOptions) {
+	opts.embedsSource = false
+	opts.state.visit(opts.sourcePath, opts.destPath)
+	elemOpts := opts
+	elemOpts.sourcePath = indexPath(opts.sourcePath, 0)
+	elemOpts.destPath = indexPath(opts.destPath, 0)
+	if _, ok := opts.converter(sourceType, destType); ok {
+		return
+	}
+	switch sourceKind, destKind := sourceType.Kind(), destType.Kind(); {
+	case opts.jsonDecode && isByteSlice(sourceType) && derefType(destType).Kind() == reflect.Struct,
+		opts.encoder != 0 && isEncodingPair(sourceType, destType),
+		opts.sqlValueTypes && (isScannerPair(sourceType, destType) || isValuerPair(sourceType, destType)):
+	case sourceKind == reflect.Ptr && destKind != reflect.Ptr && destKind != reflect.Interface:
+		c.check(sourceType.Elem(), destType, opts)
+	case sourceType == destType:
+		opts.state.complete(opts.sourcePath, opts.destPath)
+	case sourceKind == reflect.Interface:
+		// The dynamic type of the source is only known when mapping.
+	case destKind == reflect.Interface && sourceType.Implements(destType):
+	case isStringBytesPair(sourceType, destType), isByteArrayPair(sourceType, destType):
+	case destKind == reflect.Struct && isStringMap(sourceType):
+		// The keys of the source are only known when mapping.
+	case destKind == reflect.Struct && sourceKind == reflect.Struct:
+		c.checkFields(sourceType, destType, opts)
+	case sourceKind == reflect.Struct && isInterfaceMap(destType):
+	case opts.wrapSingleElements && destKind == reflect.Slice && sourceKind == reflect.Struct:
+		c.check(sourceType, destType.Elem(), elemOpts)
+	case opts.wrapSingleElements && destKind == reflect.Struct && sourceKind == reflect.Slice:
+		opts.sourcePath = elemOpts.sourcePath
+		c.check(sourceType.Elem(), destType, opts)
+	case opts.wrapScalars && destKind == reflect.Struct && isScalarKind(sourceKind):
+		field := destType.Field(opts.scalarWrapField(sourceType, destType))
+		opts.destPath = joinPath(opts.destPath, field.Name)
+		c.check(sourceType, field.Type, opts)
+	case destKind == reflect.Ptr:
+		c.check(sourceType, destType.Elem(), opts)
+	case destKind == reflect.Slice && sourceKind == reflect.Map:
+		sortedKeys(reflect.MakeMap(sourceType), opts)
+		c.check(sourceType.Elem(), destType.Elem(), elemOpts)
+	case destKind == reflect.Slice && (sourceKind == reflect.Slice || sourceKind == reflect.Array):
+		c.check(sourceType.Elem(), destType.Elem(), elemOpts)
+	case destKind == reflect.Slice:
+		panic(fmt.Sprintf("cannot map %v to %v", sourceType, destType))
+	case destKind == reflect.Array && (sourceKind == reflect.Slice || sourceKind == reflect.Array):
+		if sourceKind == reflect.Array && sourceType.Len() != destType.Len() && !opts.resizeArrays {
+			panic(fmt.Sprintf("cannot map %d elements to %v", sourceType.Len(), destType))
+		}
+		c.check(sourceType.Elem(), destType.Elem(), elemOpts)
+	case destKind == reflect.Map && sourceKind == reflect.Map:
+		if sourceType.Key() != destType.Key() {
+			c.check(sourceType.Key(), destType.Key(), elemOpts)
+		}
+		if !opts.boxesMapValues(sourceType.Elem(), destType.Elem()) {
+			c.check(sourceType.Elem(), destType.Elem(), elemOpts)
+		}
+	default:
+		opts.verifyConvertible(sourceType, destType)
+	}
+}
+
+// checkFields is the type level equivalent of mapFields.
+func (c typeChecker) checkFields(sourceType, destType reflect.Type, opts mapOptions) {
+	pair := typePair{sourceType, destType}
+	if c.checked[pair] {
+		// The fields were verified before, so they count as mapped.
+		opts.state.complete(opts.sourcePath, opts.destPath)
+		return
+	}
+	c.checked[pair] = true
+	if opts.positionalMatch {
+		if mismatch := verifyPositionalMatch(sourceType, destType); mismatch != "" {
+			panic(mismatch)
+		}
+		for i := 0; i < destType.NumField(); i++ {
+			if destType.Field(i).PkgPath != "" {
+				continue
+			}
+			fieldOpts := opts
+			fieldOpts.sourcePath = joinPath(opts.sourcePath, sourceType.Field(i).Name)
+			fieldOpts.destPath = joinPath(opts.destPath, destType.Field(i).Name)
+			c.check(sourceType.Field(i).Type, destType.Field(i).Type, fieldOpts)
+		}
+		return
+	}
+	opts.embedsSource = opts.embedsSourceType(sourceType, destType)
+	for i := 0; i < destType.NumField(); i++ {
+		c.checkDestField(sourceType, destType, i, opts)
+	}
+}
+
+// checkDestField is the type level equivalent of mapDestField.
+func (c typeChecker) checkDestField(sourceType, destType reflect.Type, i int, opts mapOptions) {
+	destField := destType.Field(i)
+	if opts.ignorePattern != nil && opts.ignorePattern.MatchString(destField.Name) {
+		return
+	}
+	tag := opts.parseTag(destField)
+	fieldDestPath := joinPath(opts.destPath, destField.Name)
+	if tag.skip {
+		opts.state.visit(joinPath(opts.sourcePath, destField.Name), fieldDestPath)
+		return
+	}
+	sourceFieldName, matched := tag.name, true
+	if opts.tagMatching {
+		sourceFieldName, matched = opts.fieldNameByMappedName(sourceType, sourceFieldName)
+	}
+	if alias, ok := opts.aliases[withoutIndexes(fieldDestPath)]; ok {
+		sourceFieldName, matched = alias, true
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			opts.recordFailure(fieldDestPath)
+			panicWithFieldContext(destField.Name, destType, sourceType, r)
+		}
+	}()
+	if opts.resolver != nil {
+		// The resolver is only called when mapping.
+		return
+	}
+	if destField.Anonymous && tag.name == destField.Name {
+		opts.destPath = fieldDestPath
+		c.check(sourceType, destField.Type, opts)
+		return
+	}
+	if !matched {
+		if tag.hasDefault {
+			setDefault(reflect.New(destField.Type).Elem(), tag.defaultValue)
+		} else if !opts.embedsSource {
+			panic(fmt.Sprintf("no source field '%s'; available: [%s]", sourceFieldName, strings.Join(exportedFieldNames(sourceType), ", ")))
+		}
+		return
+	}
+	c.checkByFieldName(sourceType, destType, destField, sourceFieldName, tag, opts)
+}
+
+// checkByFieldName is the type level equivalent of mapByFieldName.
+func (c typeChecker) checkByFieldName(sourceType, destType reflect.Type, destField reflect.StructField, sourceFieldName string, tag fieldTag, opts mapOptions) {
+	destOpts := opts
+	destOpts.destPath = joinPath(opts.destPath, destField.Name)
+	if opts.setters && destField.PkgPath != "" {
+		if setter, ok := reflect.PtrTo(destType).MethodByName("Set" + destField.Name); ok && setter.Type.NumIn() == 2 && setter.Type.NumOut() == 0 {
+			sourcePath, fieldType, found := lookupFieldType(sourceType, sourceFieldName)
+			if !found && opts.getters {
+				sourcePath, fieldType, found = lookupGetterType(sourceType, sourceFieldName)
+			}
+			if !found {
+				panic(fmt.Sprintf("no source field '%s' for setter", sourceFieldName))
+			}
+			destOpts.sourcePath = joinPath(opts.sourcePath, sourcePath)
+			c.check(fieldType, setter.Type.In(1), destOpts)
+			return
+		}
+	}
+	if tag.converterName != "" {
+		if _, ok := opts.namedConverters[tag.converterName]; !ok {
+			panic(fmt.Sprintf("no converter named '%s'", tag.converterName))
+		}
+	}
+	if tag.hasDefault {
+		setDefault(reflect.New(destField.Type).Elem(), tag.defaultValue)
+	}
+	found := false
+	// Which candidate is mapped depends on the values, so all of them are
+	// checked.
+	for _, candidate := range strings.Split(sourceFieldName, "|") {
+		if sourcePath, fieldType, ok := lookupSourceFieldType(sourceType, candidate, opts); ok {
+			found = true
+			c.checkSourceField(sourcePath, fieldType, destField, tag, destOpts)
+		}
+	}
+	if found {
+		return
+	}
+	if destField.Type.Kind() == reflect.Struct {
+		verifySettable(destField, opts)
+		c.check(sourceType, destField.Type, destOpts)
+		return
+	}
+	if sourcePath, fieldType, ok := lookupNestedFieldType(sourceType, sourceFieldName); ok {
+		if fieldType != nil {
+			c.checkSourceField(sourcePath, fieldType, destField, tag, destOpts)
+		}
+		return
+	}
+	if tag.hasDefault || opts.embedsSource {
+		return
+	}
+	panic(fmt.Sprintf("no source field '%s'; available: [%s]", sourceFieldName, strings.Join(exportedFieldNames(sourceType), ", ")))
+}
+
+// checkSourceField checks the mapping of the source field at sourcePath, of
+// type sourceType, to destField, as it is done for the given tag.
+func (c typeChecker) checkSourceField(sourcePath string, sourceType reflect.Type, destField reflect.StructField, tag fieldTag, opts mapOptions) {
+	verifySettable(destField, opts)
+	opts.sourcePath = joinPath(opts.sourcePath, sourcePath)
+	switch {
+	case tag.converterName != "":
+	case tag.timeUnit != 0:
+		verifyUnixTimePair(sourceType, destField.Type)
+	default:
+		c.check(sourceType, destField.Type, opts)
+	}
+}
+
+// verifySettable panics if the value of field can't be set, which is the case
+// for unexported fields, unless they are mapped through unsafe.
+func verifySettable(field reflect.StructField, opts mapOptions) {
+	if field.PkgPath != "" && !opts.unsafeUnexported {
+		panic(fmt.Sprintf("cannot set unexported field '%s'", field.Name))
+	}
+}
+
+// lookupSourceFieldType finds the source field with the given name like
+// mapByFieldName does, by its name, with prefixes and suffixes, or through a
+// getter if enabled.
+func lookupSourceFieldType(sourceType reflect.Type, name string, opts mapOptions) (path string, fieldType reflect.Type, ok bool) {
+	if path, fieldType, ok := lookupFieldType(sourceType, name); ok {
+		return path, fieldType, true
+	}
+	if affixed, ok := opts.affixedFieldName(sourceType, name); ok {
+		return lookupFieldType(sourceType, affixed)
+	}
+	if opts.getters {
+		return lookupGetterType(sourceType, name)
+	}
+	return "", nil, false
+}
+
+// lookupNestedFieldType is the type level equivalent of lookupNestedField.
+// fieldType is nil if the field may be held by an interface, whose dynamic
+// type is only known when mapping.
+func lookupNestedFieldType(sourceType reflect.Type, name string) (path string, fieldType reflect.Type, ok bool) {
+	var candidates []string
+	for i := 0; i < sourceType.NumField(); i++ {
+		field := sourceType.Field(i)
+		switch field.Type.Kind() {
+		case reflect.Interface:
+			ok = true
+		case reflect.Struct:
+			if nestedPath, nestedType, found := lookupFieldType(field.Type, name); found {
+				path, fieldType = joinPath(field.Name, nestedPath), nestedType
+				candidates = append(candidates, field.Name)
+			}
+		}
+	}
+	if len(candidates) > 1 {
+		panic(fmt.Sprintf("ambiguous source field '%s'; found in [%s], use a dotted path to select one", name, strings.Join(candidates, ", ")))
+	}
+	if len(candidates) == 1 {
+		return path, fieldType, true
+	}
+	return "", nil, ok
+}
+
+// lookupGetterType is the type level equivalent of lookupGetter.
+func lookupGetterType(sourceType reflect.Type, name string) (path string, fieldType reflect.Type, ok bool) {
+	for _, methodName := range []string{"Get" + name, name} {
+		method, ok := reflect.PtrTo(sourceType).MethodByName(methodName)
+		if !ok || method.Type.NumIn() != 1 || method.Type.NumOut() != 1 {
+			continue
+		}
+		return methodName + "()", method.Type.Out(0), true
+	}
+	return "", nil, false
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileMapper(t *testing.T) {
+	mapper, err := CompileMapper(reflect.TypeOf(SourceParent{}), reflect.TypeOf(&DestParent{}))
+	assert.NoError(t, err)
+
+	source := SourceParent{Children: []SourceTypeA{{Foo: 1, Bar: "a"}}}
+	dest := DestParent{}
+	mapper(source, &dest)
+	assert.Equal(t, []DestTypeA{{Foo: 1, Bar: "a"}}, dest.Children)
+
+	mapper(&SourceParent{}, &dest)
+	assert.Nil(t, dest.Children)
+}
+
+func TestCompileMapperReturnsErrorForIncompatibleTypes(t *testing.T) {
+	_, err := CompileMapper(reflect.TypeOf(SourceTypeA{}), reflect.TypeOf(struct{ Baz int }{}))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no source field 'Baz'")
+
+	_, err = CompileMapper(reflect.TypeOf(struct{ Foo []string }{}), reflect.TypeOf(struct{ Foo []int }{}))
+	assert.Error(t, err)
+
+	_, err = CompileMapper(reflect.TypeOf(0), reflect.TypeOf(DestTypeA{}))
+	assert.Error(t, err)
+}
+
+func TestCompiledMapperPanicsOnOtherTypes(t *testing.T) {
+	defer func() {
+		r := recover()
+		assert.Contains(t, r, "compiled mapper expects a source of type automapper.SourceTypeA")
+	}()
+	mapper, _ := CompileMapper(reflect.TypeOf(SourceTypeA{}), reflect.TypeOf(DestTypeA{}))
+	mapper(DestTypeA{}, &DestTypeA{})
+	t.Error("Should have panicked")
+}
+
+func TestCompileMapperWithStrict(t *testing.T) {
+	_, err := CompileMapper(reflect.TypeOf(SourceTypeA{}), reflect.TypeOf(struct{ Foo int }{}), WithStrict())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "source fields were not used: [Bar]")
+}
+
+func TestCompileMapperReturnsIncompatibleTypesError(t *testing.T) {
+	source := reflect.TypeOf(struct{ Child struct{ Foo []string } }{})
+	dest := reflect.TypeOf(struct{ Child struct{ Foo []int } }{})
+
+	_, err := CompileMapper(source, dest)
+	var incompatible *IncompatibleTypesError
+	assert.True(t, errors.As(err, &incompatible))
+	assert.Equal(t, source, incompatible.SourceType)
+	assert.Equal(t, dest, incompatible.DestType)
+	assert.Equal(t, "Child.Foo", incompatible.Path)
+
+	_, err = CompileMapper(reflect.TypeOf(0), reflect.TypeOf(DestTypeA{}))
+	assert.True(t, errors.As(err, &incompatible))
+	assert.Equal(t, "", incompatible.Path)
+}
+
+func TestCompileMapperChecksTypesBehindPointersAndElements(t *testing.T) {
+	_, err := CompileMapper(reflect.TypeOf(struct{ P *struct{ X int } }{}), reflect.TypeOf(struct{ P *struct{ Y int } }{}))
+	var incompatible *IncompatibleTypesError
+	if assert.True(t, errors.As(err, &incompatible)) {
+		assert.Equal(t, "P.Y", incompatible.Path)
+		assert.Contains(t, err.Error(), "no source field 'Y'")
+	}
+
+	_, err = CompileMapper(reflect.TypeOf(struct{ Items []SourceTypeA }{}), reflect.TypeOf(struct{ Items []struct{ Baz int } }{}))
+	assert.Error(t, err)
+
+	_, err = CompileMapper(reflect.TypeOf(struct{ Items map[string]*SourceTypeA }{}), reflect.TypeOf(struct{ Items map[string]struct{ Baz int } }{}))
+	assert.Error(t, err)
+}
+
+func TestCompileMapperDoesNotCallHooks(t *testing.T) {
+	calls := 0
+	atoi := WithConverter(reflect.TypeOf(""), reflect.TypeOf(0), func(_ context.Context, source interface{}) (interface{}, error) {
+		calls++
+		return strconv.Atoi(source.(string))
+	})
+	source := struct {
+		Child struct{ Name string }
+		Count string
+	}{}
+
+	mapper, err := CompileMapper(reflect.TypeOf(source), reflect.TypeOf(validatedParent{}), atoi, WithValidation())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, calls)
+
+	source.Child.Name, source.Count = "foo", "42"
+	dest := validatedParent{}
+	mapper(&source, &dest)
+	assert.Equal(t, validatedParent{Child: validatedChild{Name: "foo"}, Count: 42}, dest)
+	assert.Equal(t, 1, calls)
+
+	source.Child.Name = ""
+	err = SafeMap(func() { mapper(&source, &dest) })
+	assert.True(t, errors.Is(err, errEmptyName))
+}
+
+func TestCompileMapperWithRecursiveTypes(t *testing.T) {
+	type Node struct {
+		Value int
+		Next  *Node
+	}
+	type NodeDTO struct {
+		Value int
+		Next  *NodeDTO
+	}
+
+	mapper, err := CompileMapper(reflect.TypeOf(Node{}), reflect.TypeOf(NodeDTO{}), WithStrict())
+	assert.NoError(t, err)
+	dest := NodeDTO{}
+	mapper(&Node{1, &Node{Value: 2}}, &dest)
+	assert.Equal(t, NodeDTO{1, &NodeDTO{Value: 2}}, dest)
+}
+
+func TestMustCompileMapper(t *testing.T) {
+	mapper := MustCompileMapper(reflect.TypeOf(SourceTypeA{}), reflect.TypeOf(DestTypeA{}))
+	dest := DestTypeA{}
+	mapper(SourceTypeA{Foo: 1, Bar: "bar"}, &dest)
+	assert.Equal(t, DestTypeA{Foo: 1, Bar: "bar"}, dest)
+}
+
+func TestMustCompileMapperPanicsForIncompatibleTypes(t *testing.T) {
+	defer func() {
+		err, _ := recover().(error)
+		var incompatible *IncompatibleTypesError
+		assert.True(t, errors.As(err, &incompatible))
+		assert.Equal(t, "Baz", incompatible.Path)
+	}()
+	MustCompileMapper(reflect.TypeOf(SourceTypeA{}), reflect.TypeOf(struct{ Baz int }{}))
+	t.Error("Should have panicked")
+}
+
+func BenchmarkCompiledMapperFlatStruct(b *testing.B) {
+	source := flatSource{ID: 1, Name: "Name", Enabled: true, Score: 1.5}
+	dest := flatDest{}
+	mapper, err := CompileMapper(reflect.TypeOf(source), reflect.TypeOf(dest))
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < b.N; i++ {
+		mapper(&source, &dest)
+	}
+}
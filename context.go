@@ -0,0 +1,30 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import "context"
+
+// contextCheckInterval is the number of slice elements mapped between checks
+// for cancellation, keeping the overhead low for large slices.
+const contextCheckInterval = 1024
+
+// MapToDestinationContext works like MapToDestination, but stops mapping as
+// soon as ctx is done. The context is checked for every struct and
+// periodically while mapping slices. Rather than panicking, any failure is
+// returned as an error, which will match ctx.Err() when using errors.Is.
+func MapToDestinationContext(ctx context.Context, source, dest interface{}, opts ...Option) (err error) {
+	defer recoverError(&err)
+	var options = newMapOptions(false, opts)
+	options.ctx = ctx
+	mapTopLevel(source, dest, options)
+	return nil
+}
+
+func checkContext(opts mapOptions) {
+	if opts.ctx == nil {
+		return
+	}
+	if err := opts.ctx.Err(); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapToDestinationContext(t *testing.T) {
+	source, dest := SourceTypeA{Foo: 42}, DestTypeA{}
+	err := MapToDestinationContext(context.Background(), &source, &dest)
+	assert.NoError(t, err)
+	assert.Equal(t, 42, dest.Foo)
+}
+
+func TestMapToDestinationContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	source := struct {
+		Children []SourceTypeA
+	}{[]SourceTypeA{{Foo: 1}}}
+	dest := struct {
+		Children []DestTypeA
+	}{}
+	err := MapToDestinationContext(ctx, &source, &dest)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Nil(t, dest.Children)
+}
+
+func TestMapToDestinationContextReturnsMappingErrors(t *testing.T) {
+	source := struct{ Foo string }{}
+	dest := struct{ Foo int }{}
+	err := MapToDestinationContext(context.Background(), &source, &dest)
+	assert.Error(t, err)
+}
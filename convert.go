@@ -0,0 +1,265 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"reflect"
+	"unicode/utf8"
+)
+
+// ConverterFunc converts a source value to a value of the destination type it
+// is registered for with WithConverter. ctx is the context passed to
+// Mapper.MapContext or MapToDestinationContext, which lets converters use
+// request scoped data, e.g. a locale. Other mappings pass
+// context.Background().
+type ConverterFunc func(ctx context.Context, source interface{}) (interface{}, error)
+
+// Convert maps a single value to destType, following the same rules as the
+// mapping functions, including registered and built-in converters. A nil
+// source converts to the zero value of destType.
+func Convert(source interface{}, destType reflect.Type, opts ...Option) (result interface{}, err error) {
+	defer recoverError(&err)
+	dest := reflect.New(destType).Elem()
+	if source != nil {
+		mapRootValues(reflect.ValueOf(source), dest, newMapOptions(false, opts))
+	}
+	return dest.Interface(), nil
+}
+
+// ConversionError describes a value that could not be converted to the type
+// of its destination.
+type ConversionError struct {
+	// Path is the dotted path of the destination field.
+	Path string
+	// SourceType and DestType are the types of the conversion.
+	SourceType, DestType reflect.Type
+	// Err is the reason the conversion failed.
+	Err error
+}
+
+func (e *ConversionError) Error() string {
+	return fmt.Sprintf("cannot convert %v to %v at %q: %v", e.SourceType, e.DestType, e.Path, e.Err)
+}
+
+func (e *ConversionError) Unwrap() error {
+	return e.Err
+}
+
+// convertLeaf sets destVal to the result of converting sourceVal with conv.
+// If conversion errors are tolerated, a failed conversion leaves the zero
+// value in destVal instead of panicking, and is recorded as a warning.
+func convertLeaf(sourceVal, destVal reflect.Value, opts mapOptions, conv converter) {
+	if opts.defaultOnConversionError {
+		defer func() {
+			if r := recover(); r != nil {
+				destVal.Set(reflect.Zero(destVal.Type()))
+				if opts.conversionWarnings != nil {
+					*opts.conversionWarnings = append(*opts.conversionWarnings, &ConversionError{
+						Path:       opts.destPath,
+						SourceType: sourceVal.Type(),
+						DestType:   destVal.Type(),
+						Err:        errorFromPanic(r),
+					})
+				}
+			}
+		}()
+	}
+	if opts.recoverHooks {
+		defer recoverHook(destVal, opts)
+	}
+	destVal.Set(conv(opts.context(), sourceVal))
+}
+
+// context returns the context of the mapping, which is context.Background()
+// unless one was passed.
+func (o mapOptions) context() context.Context {
+	if o.ctx == nil {
+		return context.Background()
+	}
+	return o.ctx
+}
+
+// typePair identifies a conversion from a source type to a destination type.
+type typePair struct {
+	source, dest reflect.Type
+}
+
+// converter converts a source value to a value of the destination type of the
+// type pair it is registered for. It panics if the value cannot be converted.
+type converter func(ctx context.Context, sourceVal reflect.Value) reflect.Value
+
+func (o *mapOptions) addConverter(sourceType, destType reflect.Type, conv converter) {
+	if o.converters == nil {
+		o.converters = map[typePair]converter{}
+	}
+	o.converters[typePair{sourceType, destType}] = conv
+}
+
+// converter returns the converter registered for the type pair, if any.
+// Converters passed as options take precedence over the built-in converters.
+func (o *mapOptions) converter(sourceType, destType reflect.Type) (converter, bool) {
+	pair := typePair{sourceType, destType}
+	if conv, ok := o.converters[pair]; ok {
+		return conv, true
+	}
+	if conv, ok := builtinConverters[pair]; ok {
+		return conv, true
+	}
+	return o.timeConverter(sourceType, destType)
+}
+
+// WithConverter registers a function converting values of sourceType to
+// destType. It is used whenever a value of sourceType is mapped to destType,
+// and replaces any other way of mapping the two types, including the built-in
+// converters. An error returned by fn aborts the mapping. The value returned
+// must be assignable to destType, or nil for the zero value.
+func WithConverter(sourceType, destType reflect.Type, fn ConverterFunc) Option {
+	return func(o *mapOptions) {
+		o.addConverter(sourceType, destType, wrapConverterFunc(destType, fn))
+	}
+}
+
+// RegisterMapper registers fn as the converter from S to D, like
+// WithConverter does, but without spelling out the types:
+//
+//	m := NewMapper(RegisterMapper(func(c Cents) (string, error) { return c.String(), nil }))
+//
+// Like any other option, it only applies to the mapping or the Mapper it is
+// passed to.
+func RegisterMapper[S, D any](fn func(S) (D, error)) Option {
+	sourceType := reflect.TypeOf((*S)(nil)).Elem()
+	destType := reflect.TypeOf((*D)(nil)).Elem()
+	return WithConverter(sourceType, destType, func(_ context.Context, source interface{}) (interface{}, error) {
+		// A nil interface source is not an S, but its zero value.
+		s, _ := source.(S)
+		return fn(s)
+	})
+}
+
+// WithNamedConverter registers a function that converts the values of the
+// fields tagged with its name, e.g. `automapper:"Amount,conv=money"`. The
+// value returned by fn must be assignable to the type of the destination
+// field, or nil for the zero value. An error returned by fn aborts the
+// mapping, as does a tag naming a converter that is not registered.
+func WithNamedConverter(name string, fn ConverterFunc) Option {
+	return func(o *mapOptions) {
+		if o.namedConverters == nil {
+			o.namedConverters = map[string]ConverterFunc{}
+		}
+		o.namedConverters[name] = fn
+	}
+}
+
+func wrapConverterFunc(destType reflect.Type, fn ConverterFunc) converter {
+	return func(ctx context.Context, sourceVal reflect.Value) reflect.Value {
+		var result interface{}
+		var err error
+		callHook(func() { result, err = fn(ctx, sourceVal.Interface()) })
+		if err != nil {
+			panic(err)
+		}
+		resultVal := reflect.ValueOf(result)
+		if !resultVal.IsValid() {
+			return reflect.Zero(destType)
+		}
+		if !resultVal.Type().AssignableTo(destType) {
+			panic(fmt.Sprintf("converter returned %v, which is not assignable to %v", resultVal.Type(), destType))
+		}
+		return resultVal
+	}
+}
+
+// builtinConverters holds the converters that are available without being
+// registered. They convert *big.Int and *big.Rat values to and from their
+// base 10 string representation, where nil corresponds to the empty string.
+var builtinConverters = map[typePair]converter{}
+
+var stringType = reflect.TypeOf("")
+
+func init() {
+	var (
+		bigIntType = reflect.TypeOf((*big.Int)(nil))
+		bigRatType = reflect.TypeOf((*big.Rat)(nil))
+	)
+	builtinConverters[typePair{bigIntType, stringType}] = wrapConverterFunc(stringType, func(_ context.Context, source interface{}) (interface{}, error) {
+		if i := source.(*big.Int); i != nil {
+			return i.String(), nil
+		}
+		return "", nil
+	})
+	builtinConverters[typePair{stringType, bigIntType}] = wrapConverterFunc(bigIntType, func(_ context.Context, source interface{}) (interface{}, error) {
+		if source == "" {
+			return nil, nil
+		}
+		if i, ok := new(big.Int).SetString(source.(string), 10); ok {
+			return i, nil
+		}
+		return nil, fmt.Errorf("invalid integer %q", source)
+	})
+	builtinConverters[typePair{bigRatType, stringType}] = wrapConverterFunc(stringType, func(_ context.Context, source interface{}) (interface{}, error) {
+		if r := source.(*big.Rat); r != nil {
+			return r.RatString(), nil
+		}
+		return "", nil
+	})
+	builtinConverters[typePair{stringType, bigRatType}] = wrapConverterFunc(bigRatType, func(_ context.Context, source interface{}) (interface{}, error) {
+		if source == "" {
+			return nil, nil
+		}
+		if r, ok := new(big.Rat).SetString(source.(string)); ok {
+			return r, nil
+		}
+		return nil, fmt.Errorf("invalid rational number %q", source)
+	})
+}
+
+// isCharacterPair returns true when one of the types is a string and the other
+// is a rune or a byte, i.e. of kind int32 or uint8, in either direction.
+func isCharacterPair(sourceType, destType reflect.Type) bool {
+	return sourceType.Kind() == reflect.String && isCharacterKind(destType.Kind()) ||
+		isCharacterKind(sourceType.Kind()) && destType.Kind() == reflect.String
+}
+
+func isCharacterKind(kind reflect.Kind) bool {
+	return kind == reflect.Int32 || kind == reflect.Uint8
+}
+
+// convertCharacter converts between a string and a single character. A string
+// converts to a rune only if it holds exactly one valid UTF-8 encoded
+// character, and to a byte only if it is exactly one byte long. The empty
+// string converts to the zero value, as it holds no character. Other strings
+// cause a panic, rather than silently losing all but one character. A rune
+// converts to its UTF-8 encoding, and must be a valid code point, while a
+// byte converts to the string holding just that byte, which is not valid
+// UTF-8 for bytes from 0x80.
+func convertCharacter(sourceVal reflect.Value, destType reflect.Type) reflect.Value {
+	result := reflect.New(destType).Elem()
+	switch {
+	case sourceVal.Kind() == reflect.String && sourceVal.Len() == 0:
+	case sourceVal.Kind() == reflect.Int32:
+		r := rune(sourceVal.Int())
+		if !utf8.ValidRune(r) {
+			panic(fmt.Sprintf("cannot convert %d to %v: not a valid character", r, destType))
+		}
+		result.SetString(string(r))
+	case sourceVal.Kind() == reflect.Uint8:
+		result.SetString(string([]byte{byte(sourceVal.Uint())}))
+	case destType.Kind() == reflect.Int32:
+		s := sourceVal.String()
+		r, size := utf8.DecodeRuneInString(s)
+		if size == 0 || size != len(s) || r == utf8.RuneError && size == 1 {
+			panic(fmt.Sprintf("cannot convert %q to %v: it must hold exactly one character", s, destType))
+		}
+		result.SetInt(int64(r))
+	default:
+		s := sourceVal.String()
+		if len(s) != 1 {
+			panic(fmt.Sprintf("cannot convert %q to %v: it must be exactly one byte long", s, destType))
+		}
+		result.SetUint(uint64(s[0]))
+	}
+	return result
+}
@@ -0,0 +1,331 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithConverter(t *testing.T) {
+	source := struct{ Foo string }{"42"}
+	dest := struct{ Foo int }{}
+	atoi := WithConverter(reflect.TypeOf(""), reflect.TypeOf(0), func(_ context.Context, source interface{}) (interface{}, error) {
+		return strconv.Atoi(source.(string))
+	})
+
+	MapToDestination(&source, &dest, atoi)
+	assert.Equal(t, 42, dest.Foo)
+
+	source.Foo = "abc"
+	err := NewMapper(atoi).MapDir(&source, &dest, ToDestination)
+	assert.True(t, errors.Is(err, strconv.ErrSyntax))
+}
+
+func TestBigIntConverters(t *testing.T) {
+	source := struct {
+		Amount *big.Int
+		Empty  *big.Int
+	}{Amount: big.NewInt(0).Lsh(big.NewInt(1), 100)}
+	dest := struct {
+		Amount string
+		Empty  string
+	}{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, "1267650600228229401496703205376", dest.Amount)
+	assert.Equal(t, "", dest.Empty)
+
+	source.Amount = nil
+	MapToDestination(&dest, &source)
+	assert.Equal(t, 0, source.Amount.Cmp(big.NewInt(0).Lsh(big.NewInt(1), 100)))
+	assert.Nil(t, source.Empty)
+}
+
+func TestBigRatConverters(t *testing.T) {
+	source := struct{ Amount *big.Rat }{big.NewRat(1, 3)}
+	dest := struct{ Amount string }{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, "1/3", dest.Amount)
+
+	source.Amount = nil
+	MapToDestination(&dest, &source)
+	assert.Equal(t, big.NewRat(1, 3), source.Amount)
+}
+
+func TestBigIntConverterInvalidValue(t *testing.T) {
+	source := struct{ Amount string }{"abc"}
+	dest := struct{ Amount *big.Int }{}
+
+	err := NewMapper().MapDir(&source, &dest, ToDestination)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid integer "abc"`)
+}
+
+func TestBuiltinConvertersCanBeOverridden(t *testing.T) {
+	source := struct{ Amount *big.Int }{big.NewInt(255)}
+	dest := struct{ Amount string }{}
+	hex := WithConverter(reflect.TypeOf(&big.Int{}), reflect.TypeOf(""), func(_ context.Context, source interface{}) (interface{}, error) {
+		return source.(*big.Int).Text(16), nil
+	})
+
+	MapToDestination(&source, &dest, hex)
+	assert.Equal(t, "ff", dest.Amount)
+}
+
+func TestWithDefaultOnConversionError(t *testing.T) {
+	source := struct {
+		Foo string
+		Bar string
+		Baz int64
+	}{"42", "abc", 1 << 40}
+	dest := struct {
+		Foo int
+		Bar int
+		Baz int32
+	}{1, 2, 3}
+	atoi := WithConverter(reflect.TypeOf(""), reflect.TypeOf(0), func(_ context.Context, source interface{}) (interface{}, error) {
+		return strconv.Atoi(source.(string))
+	})
+	var warnings []error
+
+	MapToDestination(&source, &dest, atoi, WithOverflowCheck(), WithDefaultOnConversionError(&warnings))
+	assert.Equal(t, 42, dest.Foo)
+	assert.Equal(t, 0, dest.Bar)
+	assert.Equal(t, int32(0), dest.Baz)
+	if assert.Len(t, warnings, 2) {
+		var conversionError *ConversionError
+		assert.True(t, errors.As(warnings[0], &conversionError))
+		assert.Equal(t, "Bar", conversionError.Path)
+		assert.True(t, errors.Is(warnings[0], strconv.ErrSyntax))
+		assert.Contains(t, warnings[1].Error(), "overflows int32")
+	}
+}
+
+func TestWithDefaultOnConversionErrorStillFailsOnMissingFields(t *testing.T) {
+	source := struct{ Foo string }{}
+	dest := struct{ Foo, Bar string }{}
+
+	err := NewMapper(WithDefaultOnConversionError(nil)).MapDir(&source, &dest, ToDestination)
+	assert.Error(t, err)
+}
+
+func TestWithDefaultOnConversionErrorStillFailsOnMismatchedTypes(t *testing.T) {
+	source := struct{ Foo SourceTypeA }{}
+	dest := struct{ Foo int }{}
+	var warnings []error
+
+	err := NewMapper(WithDefaultOnConversionError(&warnings)).MapDir(&source, &dest, ToDestination)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "cannot convert automapper.SourceTypeA to int")
+	}
+	assert.Empty(t, warnings)
+}
+
+func TestConvert(t *testing.T) {
+	result, err := Convert(int64(42), reflect.TypeOf(int8(0)))
+	assert.NoError(t, err)
+	assert.Equal(t, int8(42), result)
+
+	result, err = Convert("12345678901234567890", reflect.TypeOf(&big.Int{}))
+	assert.NoError(t, err)
+	assert.Equal(t, "12345678901234567890", result.(*big.Int).String())
+
+	result, err = Convert(SourceTypeA{Foo: 1, Bar: "a"}, reflect.TypeOf(DestTypeA{}))
+	assert.NoError(t, err)
+	assert.Equal(t, DestTypeA{Foo: 1, Bar: "a"}, result)
+}
+
+func TestConvertNilReturnsZeroValue(t *testing.T) {
+	result, err := Convert(nil, reflect.TypeOf(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "", result)
+}
+
+func TestConvertReturnsError(t *testing.T) {
+	_, err := Convert(int64(1000), reflect.TypeOf(int8(0)), WithOverflowCheck())
+	assert.EqualError(t, err, "value 1000 overflows int8")
+
+	_, err = Convert([]int{1}, reflect.TypeOf(""))
+	assert.Error(t, err)
+}
+
+func TestConverterFromStructToScalar(t *testing.T) {
+	source := struct{ Price *money }{&money{"USD", 1234}}
+	dest := struct{ Price string }{}
+	moneyToString := WithConverter(reflect.TypeOf(money{}), reflect.TypeOf(""), func(_ context.Context, source interface{}) (interface{}, error) {
+		m := source.(money)
+		return fmt.Sprintf("%d.%02d %s", m.Amount/100, m.Amount%100, m.Currency), nil
+	})
+
+	MapToDestination(&source, &dest, moneyToString)
+	assert.Equal(t, "12.34 USD", dest.Price)
+}
+
+func TestConverterFromScalarToStruct(t *testing.T) {
+	source := struct{ Price string }{"1234 USD"}
+	dest := struct{ Price money }{}
+	stringToMoney := WithConverter(reflect.TypeOf(""), reflect.TypeOf(money{}), func(_ context.Context, source interface{}) (interface{}, error) {
+		var m money
+		_, err := fmt.Sscanf(source.(string), "%d %s", &m.Amount, &m.Currency)
+		return m, err
+	})
+
+	MapToDestination(&source, &dest, stringToMoney)
+	assert.Equal(t, money{"USD", 1234}, dest.Price)
+}
+
+var moneyConverter = WithNamedConverter("money", func(_ context.Context, source interface{}) (interface{}, error) {
+	cents := source.(int64)
+	return fmt.Sprintf("%d.%02d", cents/100, cents%100), nil
+})
+
+func TestWithNamedConverter(t *testing.T) {
+	source := struct {
+		Amount int64
+		Count  int64
+	}{1234, 5}
+	dest := struct {
+		Amount string `automapper:",conv=money"`
+		Count  int
+	}{}
+
+	MapToDestination(&source, &dest, moneyConverter)
+	assert.Equal(t, "12.34", dest.Amount)
+	assert.Equal(t, 5, dest.Count)
+}
+
+func TestWithNamedConverterOnSourceField(t *testing.T) {
+	source := struct {
+		Amount int64 `automapper:"Price,conv=money"`
+	}{1234}
+	dest := struct{ Price string }{}
+
+	MapFromSource(&source, &dest, moneyConverter)
+	assert.Equal(t, "12.34", dest.Price)
+}
+
+func TestMissingNamedConverter(t *testing.T) {
+	source := struct{ Amount int64 }{1234}
+	dest := struct {
+		Amount string `automapper:",conv=money"`
+	}{}
+
+	err := SafeMap(func() { MapToDestination(&source, &dest) })
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no converter named 'money'")
+
+	_, err = CompileMapper(reflect.TypeOf(source), reflect.TypeOf(dest))
+	assert.Error(t, err)
+}
+
+func TestConvertCharacters(t *testing.T) {
+	type grade byte
+	source := struct {
+		Initial string
+		Grade   string
+		Empty   string
+		Rune    rune
+		Byte    byte
+	}{"é", "A", "", 'é', 0xe9}
+	dest := struct {
+		Initial rune
+		Grade   grade
+		Empty   rune
+		Rune    string
+		Byte    string
+	}{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, 'é', dest.Initial)
+	assert.Equal(t, grade('A'), dest.Grade)
+	assert.Equal(t, rune(0), dest.Empty)
+	assert.Equal(t, "é", dest.Rune)
+	assert.Equal(t, "\xe9", dest.Byte)
+}
+
+func TestConvertCharactersRejectsOtherLengths(t *testing.T) {
+	_, err := Convert("ab", reflect.TypeOf('a'))
+	assert.EqualError(t, err, `cannot convert "ab" to int32: it must hold exactly one character`)
+
+	_, err = Convert("é", reflect.TypeOf(byte(0)))
+	assert.EqualError(t, err, `cannot convert "é" to uint8: it must be exactly one byte long`)
+
+	_, err = Convert("\xff", reflect.TypeOf('a'))
+	assert.Error(t, err)
+
+	_, err = Convert(rune(-1), reflect.TypeOf(""))
+	assert.EqualError(t, err, "cannot convert -1 to string: not a valid character")
+}
+
+func TestConvertIncompatibleTypes(t *testing.T) {
+	_, err := Convert(SourceTypeA{}, reflect.TypeOf(0))
+	assert.EqualError(t, err, "cannot convert automapper.SourceTypeA to int; register a converter to map them")
+
+	source := struct{ Foo []int }{[]int{1}}
+	dest := struct{ Foo bool }{}
+	err = NewMapper().MapDir(&source, &dest, ToDestination)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Error mapping field: Foo.")
+	assert.Contains(t, err.Error(), "cannot convert []int to bool")
+}
+
+func TestCompileMapperAllowsCharacters(t *testing.T) {
+	_, err := CompileMapper(reflect.TypeOf(struct{ Code string }{}), reflect.TypeOf(struct{ Code rune }{}))
+	assert.NoError(t, err)
+}
+
+type cents int64
+
+func TestRegisterMapper(t *testing.T) {
+	format := RegisterMapper(func(c cents) (string, error) {
+		return fmt.Sprintf("%d.%02d", c/100, c%100), nil
+	})
+	source := struct{ Price, Discount cents }{1234, 5}
+	dest := struct{ Price, Discount string }{}
+
+	err := NewMapper(format).MapDir(&source, &dest, ToDestination)
+	assert.NoError(t, err)
+	assert.Equal(t, "12.34", dest.Price)
+	assert.Equal(t, "0.05", dest.Discount)
+}
+
+func TestRegisterMapperIsScopedToMapper(t *testing.T) {
+	parse := RegisterMapper(func(s string) (cents, error) {
+		f, err := strconv.ParseFloat(s, 64)
+		return cents(f * 100), err
+	})
+	source := struct{ Price string }{"1.5"}
+	dest := struct{ Price cents }{}
+
+	assert.NoError(t, NewMapper(parse).MapDir(&source, &dest, ToDestination))
+	assert.Equal(t, cents(150), dest.Price)
+	assert.Error(t, NewMapper().MapDir(&source, &dest, ToDestination))
+
+	source.Price = "free"
+	err := NewMapper(parse).MapDir(&source, &dest, ToDestination)
+	assert.True(t, errors.Is(err, strconv.ErrSyntax))
+}
+
+func TestRegisterMapperWithInterfaceSource(t *testing.T) {
+	describe := RegisterMapper(func(s fmt.Stringer) (string, error) {
+		if s == nil {
+			return "none", nil
+		}
+		return "described " + s.String(), nil
+	})
+	source := struct{ Value, Nil fmt.Stringer }{Value: big.NewInt(42)}
+	dest := struct{ Value, Nil string }{}
+
+	MapToDestination(&source, &dest, describe)
+	assert.Equal(t, "described 42", dest.Value)
+	assert.Equal(t, "none", dest.Nil)
+}
@@ -0,0 +1,204 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"reflect"
+	"sync"
+)
+
+// deepCopy returns a copy of value of the same type that shares no pointers,
+// slices or maps with it. Interface values are copied according to their
+// dynamic type, except for errors, which are kept as they are. Unexported
+// struct fields can't be copied through reflection, so they are copied as they
+// are, and pointers to values holding such fields, e.g. a *big.Int or a
+// *sync.Mutex, are kept as they are unless a converter is registered for the
+// pointer type. A pointer that occurs several times in value is copied once,
+// which also copies cycles. Like mapValues, deepCopy fails once the maximum
+// depth is exceeded or the context is done.
+func deepCopy(value reflect.Value, opts mapOptions) reflect.Value {
+	c := copier{opts, map[pointerKey]reflect.Value{}}
+	return c.copy(value, opts.depth)
+}
+
+// copier holds the pointers copied by a single deepCopy. Pointers are shared
+// with the mapping as well, if it preserves shared pointers.
+type copier struct {
+	opts   mapOptions
+	copies map[pointerKey]reflect.Value
+}
+
+// copy returns a copy of value, which is nested depth levels deep in the
+// mapping.
+func (c copier) copy(value reflect.Value, depth int) reflect.Value {
+	c.opts.verifyDepth(depth)
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			return value
+		}
+		if hasUnexportedState(value.Type().Elem()) {
+			return c.copyOpaque(value)
+		}
+		if shared, ok := c.opts.state.sharedPointer(value, value.Type()); ok {
+			return shared
+		}
+		key := pointerKey{value.Pointer(), value.Type(), value.Type()}
+		if copied, ok := c.copies[key]; ok {
+			return copied
+		}
+		result := reflect.New(value.Type().Elem())
+		c.copies[key] = result
+		c.opts.state.sharePointer(value, result)
+		result.Elem().Set(c.copy(value.Elem(), depth+1))
+		return result
+	case reflect.Interface:
+		// Errors are kept, as errors.Is compares them to sentinel values.
+		if value.IsNil() || value.Elem().Type().Implements(errorType) {
+			return value
+		}
+		result := reflect.New(value.Type()).Elem()
+		result.Set(c.copy(value.Elem(), depth+1))
+		return result
+	case reflect.Slice:
+		if value.IsNil() {
+			return value
+		}
+		result := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+		for i := 0; i < value.Len(); i++ {
+			if i%contextCheckInterval == 0 {
+				checkContext(c.opts)
+			}
+			result.Index(i).Set(c.copy(value.Index(i), depth+1))
+		}
+		return result
+	case reflect.Array:
+		result := reflect.New(value.Type()).Elem()
+		for i := 0; i < value.Len(); i++ {
+			if i%contextCheckInterval == 0 {
+				checkContext(c.opts)
+			}
+			result.Index(i).Set(c.copy(value.Index(i), depth+1))
+		}
+		return result
+	case reflect.Map:
+		if value.IsNil() {
+			return value
+		}
+		result := reflect.MakeMapWithSize(value.Type(), value.Len())
+		iter := value.MapRange()
+		for i := 0; iter.Next(); i++ {
+			if i%contextCheckInterval == 0 {
+				checkContext(c.opts)
+			}
+			result.SetMapIndex(iter.Key(), c.copy(iter.Value(), depth+1))
+		}
+		return result
+	case reflect.Struct:
+		checkContext(c.opts)
+		result := reflect.New(value.Type()).Elem()
+		result.Set(value)
+		for i := 0; i < value.NumField(); i++ {
+			if value.Type().Field(i).PkgPath == "" {
+				result.Field(i).Set(c.copy(value.Field(i), depth+1))
+			}
+		}
+		return result
+	}
+	return value
+}
+
+// copyOpaque returns a copy of the pointer value made by the converter
+// registered for its type, or else value itself, as the value it points to
+// can't be copied through reflection.
+func (c copier) copyOpaque(value reflect.Value) reflect.Value {
+	conv, ok := c.opts.converter(value.Type(), value.Type())
+	if !ok {
+		return value
+	}
+	result := reflect.New(value.Type()).Elem()
+	convertLeaf(value, result, c.opts, conv)
+	return result
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// pointerTypes caches the result of containsPointers.
+var pointerTypes sync.Map
+
+// containsPointers returns true if values of t hold pointers, directly or in
+// their exported fields and elements, so assigning them would share the values
+// pointed to. Interfaces are not looked into, as their
+// values are assigned as they are unless they are deep copied.
+func containsPointers(t reflect.Type) bool {
+	if result, ok := pointerTypes.Load(t); ok {
+		return result.(bool)
+	}
+	result := typeContainsPointers(t, map[reflect.Type]bool{})
+	pointerTypes.Store(t, result)
+	return result
+}
+
+// typeContainsPointers implements containsPointers. Types in visiting are
+// being inspected already, so recursive types terminate.
+func typeContainsPointers(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	if visiting[t] {
+		return false
+	}
+	visiting[t] = true
+	switch t.Kind() {
+	case reflect.Ptr:
+		return true
+	case reflect.Slice, reflect.Array:
+		return typeContainsPointers(t.Elem(), visiting)
+	case reflect.Map:
+		return typeContainsPointers(t.Key(), visiting) || typeContainsPointers(t.Elem(), visiting)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if field := t.Field(i); field.PkgPath == "" && typeContainsPointers(field.Type, visiting) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// unexportedStateTypes caches the result of hasUnexportedState.
+var unexportedStateTypes sync.Map
+
+// hasUnexportedState returns true if values of t hold unexported struct
+// fields, directly or in the exported fields and elements that deepCopy copies
+// along with them. Such values can't be copied faithfully, e.g. a big.Int
+// would share its digits with the original, and a copied sync.Mutex would
+// still be locked.
+func hasUnexportedState(t reflect.Type) bool {
+	if result, ok := unexportedStateTypes.Load(t); ok {
+		return result.(bool)
+	}
+	result := typeHasUnexportedState(t, map[reflect.Type]bool{})
+	unexportedStateTypes.Store(t, result)
+	return result
+}
+
+// typeHasUnexportedState implements hasUnexportedState. Pointers and
+// interfaces are not looked into, as deepCopy decides how to copy the values
+// they hold on its own.
+func typeHasUnexportedState(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	if visiting[t] {
+		return false
+	}
+	visiting[t] = true
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return typeHasUnexportedState(t.Elem(), visiting)
+	case reflect.Map:
+		return typeHasUnexportedState(t.Key(), visiting) || typeHasUnexportedState(t.Elem(), visiting)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if field := t.Field(i); field.PkgPath != "" || typeHasUnexportedState(field.Type, visiting) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type userCreated struct {
+	Name string
+	Tags []string
+}
+
+type orderPlaced struct {
+	ID    int
+	Lines map[string]int
+}
+
+func TestWithDeepCopyCopiesHeterogeneousInterfaceSlices(t *testing.T) {
+	source := struct{ Events []interface{} }{[]interface{}{
+		&userCreated{Name: "foo", Tags: []string{"a"}},
+		orderPlaced{ID: 1, Lines: map[string]int{"x": 2}},
+		"note",
+		nil,
+	}}
+	dest := struct{ Events []interface{} }{}
+
+	MapToDestination(&source, &dest, WithDeepCopy())
+	assert.Equal(t, source.Events, dest.Events)
+
+	user := dest.Events[0].(*userCreated)
+	assert.NotSame(t, source.Events[0], user)
+	user.Tags[0] = "b"
+	dest.Events[1].(orderPlaced).Lines["x"] = 3
+	assert.Equal(t, "a", source.Events[0].(*userCreated).Tags[0])
+	assert.Equal(t, 2, source.Events[1].(orderPlaced).Lines["x"])
+}
+
+type codeError struct {
+	Code int
+}
+
+func (e *codeError) Error() string {
+	return fmt.Sprintf("code %d", e.Code)
+}
+
+func TestWithDeepCopyKeepsErrors(t *testing.T) {
+	type Result struct {
+		Err    error
+		Events []interface{}
+	}
+	notFound := &codeError{Code: 404}
+	source := Result{fmt.Errorf("reading: %w", io.EOF), []interface{}{notFound}}
+	dest := Result{}
+
+	MapToDestination(&source, &dest, WithDeepCopy())
+	assert.True(t, errors.Is(dest.Err, io.EOF))
+	assert.Same(t, notFound, dest.Events[0])
+}
+
+func TestMapWithoutDeepCopySharesInterfaceElements(t *testing.T) {
+	user := &userCreated{Name: "foo"}
+	source := struct{ Events []interface{} }{[]interface{}{user}}
+	dest := struct{ Events []interface{} }{}
+
+	MapToDestination(&source, &dest)
+	assert.Same(t, user, dest.Events[0])
+}
+
+func TestWithDeepCopyCopiesNestedValuesOfIdenticalTypes(t *testing.T) {
+	type Node struct {
+		Value    int
+		Children []*Node
+	}
+	source := struct{ Root *Node }{&Node{1, []*Node{{Value: 2}}}}
+	dest := struct{ Root *Node }{}
+
+	MapToDestination(&source, &dest, WithDeepCopy())
+	assert.Equal(t, source.Root, dest.Root)
+	assert.NotSame(t, source.Root, dest.Root)
+	assert.NotSame(t, source.Root.Children[0], dest.Root.Children[0])
+}
+
+func TestWithDeepCopyAndSharedPointersCopiesCycles(t *testing.T) {
+	type Node struct {
+		Next *Node
+	}
+	node := &Node{}
+	node.Next = node
+	source := struct{ Node *Node }{node}
+	dest := struct{ Node *Node }{}
+
+	MapToDestination(&source, &dest, WithDeepCopy(), WithSharedPointers())
+	assert.NotSame(t, node, dest.Node)
+	assert.Same(t, dest.Node, dest.Node.Next)
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// FieldDiff describes a value that differs between the two values passed to
+// Diff.
+type FieldDiff struct {
+	// Path is the dotted path of the value, where slice elements and map
+	// entries are given in brackets, e.g. "Items[2].Name".
+	Path string
+	// Old is the value in a, or nil if it only exists in b, e.g. for an
+	// element appended to a slice. New is the value in b, or nil if it only
+	// exists in a.
+	Old, New interface{}
+}
+
+// Diff returns the values that differ between a and b, e.g. to record what
+// changed when saving an entity. If b has another type than a, it is first
+// mapped to the type of a, like MapToDestination does with opts, so only the
+// fields of a are compared.
+//
+// Structs are compared field by field, and slices and arrays element by
+// element. Maps are compared entry by entry, and pointers and interfaces by the
+// values they hold. Other values are compared with reflect.DeepEqual, except
+// for times, which are compared with time.Time.Equal. Unexported fields are
+// not compared. The differences are returned in the order of the fields, with
+// map entries sorted by their keys, see WithKeyLess. Keys without a natural
+// order are sorted by their formatted values.
+//
+// An error is returned if a or b is nil, or if b can't be mapped to the type
+// of a.
+func Diff(a, b interface{}, opts ...Option) (diffs []FieldDiff, err error) {
+	defer recoverError(&err)
+	if a == nil || b == nil {
+		return nil, errors.New("cannot diff nil values")
+	}
+	options := newMapOptions(false, opts)
+	aVal, bVal := reflect.ValueOf(a), reflect.ValueOf(b)
+	if aVal.Type() != bVal.Type() {
+		target := reflect.New(aVal.Type())
+		mapTopLevel(b, target.Interface(), options)
+		bVal = target.Elem()
+	}
+	d := differ{opts: options, visited: map[diffKey]bool{}}
+	d.diff(aVal, bVal, "")
+	return d.diffs, nil
+}
+
+// diffKey identifies a pair of pointers compared by Diff, so cycles are only
+// compared once.
+type diffKey struct {
+	a, b uintptr
+	t    reflect.Type
+}
+
+// differ collects the differences found by a single call to Diff.
+type differ struct {
+	opts    mapOptions
+	diffs   []FieldDiff
+	visited map[diffKey]bool
+}
+
+func (d *differ) diff(a, b reflect.Value, path string) {
+	switch a.Kind() {
+	case reflect.Ptr:
+		if a.IsNil() || b.IsNil() {
+			d.compare(a, b, path)
+			return
+		}
+		key := diffKey{a.Pointer(), b.Pointer(), a.Type()}
+		if d.visited[key] {
+			return
+		}
+		d.visited[key] = true
+		d.diff(a.Elem(), b.Elem(), path)
+	case reflect.Interface:
+		if a.IsNil() || b.IsNil() || a.Elem().Type() != b.Elem().Type() {
+			d.compare(a, b, path)
+			return
+		}
+		d.diff(a.Elem(), b.Elem(), path)
+	case reflect.Struct:
+		if a.Type() == timeType {
+			if !a.Interface().(time.Time).Equal(b.Interface().(time.Time)) {
+				d.add(path, a.Interface(), b.Interface())
+			}
+			return
+		}
+		for i := 0; i < a.NumField(); i++ {
+			if field := a.Type().Field(i); field.PkgPath == "" {
+				d.diff(a.Field(i), b.Field(i), joinPath(path, field.Name))
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		length := a.Len()
+		if b.Len() > length {
+			length = b.Len()
+		}
+		for i := 0; i < length; i++ {
+			switch {
+			case i >= a.Len():
+				d.add(indexPath(path, i), nil, b.Index(i).Interface())
+			case i >= b.Len():
+				d.add(indexPath(path, i), a.Index(i).Interface(), nil)
+			default:
+				d.diff(a.Index(i), b.Index(i), indexPath(path, i))
+			}
+		}
+	case reflect.Map:
+		for _, key := range d.mapKeys(a, b) {
+			aElem, bElem := a.MapIndex(key), b.MapIndex(key)
+			switch {
+			case !aElem.IsValid():
+				d.add(keyPath(path, key), nil, bElem.Interface())
+			case !bElem.IsValid():
+				d.add(keyPath(path, key), aElem.Interface(), nil)
+			default:
+				d.diff(aElem, bElem, keyPath(path, key))
+			}
+		}
+	default:
+		d.compare(a, b, path)
+	}
+}
+
+// mapKeys returns the keys of both maps a and b, in order.
+func (d *differ) mapKeys(a, b reflect.Value) []reflect.Value {
+	keyType := a.Type().Key()
+	keys := reflect.MakeMap(reflect.MapOf(keyType, reflect.TypeOf(struct{}{})))
+	for _, m := range []reflect.Value{a, b} {
+		for _, key := range m.MapKeys() {
+			keys.SetMapIndex(key, reflect.ValueOf(struct{}{}))
+		}
+	}
+	if kind := keyType.Kind(); d.opts.keyLess != nil || kind == reflect.String || isNumericKind(kind) {
+		return sortedKeys(keys, d.opts)
+	}
+	result := keys.MapKeys()
+	sort.Slice(result, func(i, j int) bool { return fmt.Sprint(result[i]) < fmt.Sprint(result[j]) })
+	return result
+}
+
+// compare records a difference if a and b are not deeply equal.
+func (d *differ) compare(a, b reflect.Value, path string) {
+	if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+		d.add(path, a.Interface(), b.Interface())
+	}
+}
+
+func (d *differ) add(path string, oldValue, newValue interface{}) {
+	d.diffs = append(d.diffs, FieldDiff{Path: path, Old: oldValue, New: newValue})
+}
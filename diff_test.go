@@ -0,0 +1,101 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type diffOrder struct {
+	ID       int
+	Customer *DestTypeA
+	Lines    []DestTypeA
+	Tags     map[string]int
+	Note     interface{}
+	Placed   time.Time
+	internal string
+}
+
+func TestDiff(t *testing.T) {
+	placed := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	a := diffOrder{
+		ID:       1,
+		Customer: &DestTypeA{Foo: 1, Bar: "old"},
+		Lines:    []DestTypeA{{Foo: 1}, {Foo: 2}},
+		Tags:     map[string]int{"a": 1, "b": 2},
+		Note:     "note",
+		Placed:   placed,
+		internal: "a",
+	}
+	b := diffOrder{
+		ID:       1,
+		Customer: &DestTypeA{Foo: 1, Bar: "new"},
+		Lines:    []DestTypeA{{Foo: 1}, {Foo: 3}, {Foo: 4}},
+		Tags:     map[string]int{"b": 3, "c": 4},
+		Note:     42,
+		Placed:   placed.In(time.FixedZone("CET", 3600)),
+		internal: "b",
+	}
+
+	diffs, err := Diff(a, b)
+	assert.NoError(t, err)
+	assert.Equal(t, []FieldDiff{
+		{Path: "Customer.Bar", Old: "old", New: "new"},
+		{Path: "Lines[1].Foo", Old: 2, New: 3},
+		{Path: "Lines[2]", Old: nil, New: DestTypeA{Foo: 4}},
+		{Path: "Tags[a]", Old: 1, New: nil},
+		{Path: "Tags[b]", Old: 2, New: 3},
+		{Path: "Tags[c]", Old: nil, New: 4},
+		{Path: "Note", Old: "note", New: 42},
+	}, diffs)
+}
+
+func TestDiffOfEqualValues(t *testing.T) {
+	a := diffOrder{Customer: &DestTypeA{Foo: 1}, Lines: []DestTypeA{}}
+	b := diffOrder{Customer: &DestTypeA{Foo: 1}}
+
+	diffs, err := Diff(&a, &b)
+	assert.NoError(t, err)
+	assert.Empty(t, diffs)
+}
+
+func TestDiffOfNilPointers(t *testing.T) {
+	a := diffOrder{}
+	b := diffOrder{Customer: &DestTypeA{Foo: 1}}
+
+	diffs, err := Diff(a, b)
+	assert.NoError(t, err)
+	assert.Equal(t, []FieldDiff{{Path: "Customer", Old: (*DestTypeA)(nil), New: &DestTypeA{Foo: 1}}}, diffs)
+}
+
+func TestDiffMapsOtherTypes(t *testing.T) {
+	a := DestTypeA{Foo: 1, Bar: "bar"}
+	b := SourceTypeA{Foo: 2, Bar: "bar"}
+
+	diffs, err := Diff(a, b)
+	assert.NoError(t, err)
+	assert.Equal(t, []FieldDiff{{Path: "Foo", Old: 1, New: 2}}, diffs)
+}
+
+func TestDiffWithCycles(t *testing.T) {
+	a := &graphNode{Name: "a"}
+	a.Children = []*graphNode{a}
+	b := &graphNode{Name: "b"}
+	b.Children = []*graphNode{b}
+
+	diffs, err := Diff(a, b)
+	assert.NoError(t, err)
+	assert.Equal(t, []FieldDiff{{Path: "Name", Old: "a", New: "b"}}, diffs)
+}
+
+func TestDiffOfNilValuesFails(t *testing.T) {
+	_, err := Diff(nil, DestTypeA{})
+	assert.EqualError(t, err, "cannot diff nil values")
+	_, err = Diff(DestTypeA{}, nil)
+	assert.Error(t, err)
+	_, err = Diff(DestTypeA{}, struct{ Baz int }{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Encoder selects the encoding used by WithEncoder.
+type Encoder int
+
+const (
+	// EncoderJSON encodes values with encoding/json.
+	EncoderJSON Encoder = iota + 1
+	// EncoderGob encodes values with encoding/gob.
+	EncoderGob
+)
+
+// isEncodingPair returns true if values of one of the types are encoded into
+// values of the other one, which is the case for byte slices and (pointers to)
+// structs.
+func isEncodingPair(sourceType, destType reflect.Type) bool {
+	return isByteSlice(destType) && derefType(sourceType).Kind() == reflect.Struct ||
+		isByteSlice(sourceType) && derefType(destType).Kind() == reflect.Struct
+}
+
+// mapEncoded encodes the struct sourceVal into the byte slice destVal, or
+// decodes the byte slice sourceVal into the struct destVal. A nil pointer
+// encodes to a nil byte slice, and an empty byte slice leaves the destination
+// unchanged. Errors cause a panic.
+func mapEncoded(sourceVal, destVal reflect.Value, encoder Encoder) {
+	if isByteSlice(destVal.Type()) {
+		if valueIsNil(sourceVal) {
+			destVal.Set(reflect.Zero(destVal.Type()))
+			return
+		}
+		data, err := encode(sourceVal.Interface(), encoder)
+		if err != nil {
+			panic(fmt.Errorf("cannot encode %v: %w", sourceVal.Type(), err))
+		}
+		destVal.Set(reflect.ValueOf(data).Convert(destVal.Type()))
+		return
+	}
+	data := sourceVal.Bytes()
+	if len(data) == 0 {
+		return
+	}
+	target := reflect.New(destVal.Type())
+	if err := decode(data, target.Interface(), encoder); err != nil {
+		panic(fmt.Errorf("cannot decode %v: %w", destVal.Type(), err))
+	}
+	destVal.Set(target.Elem())
+}
+
+func encode(value interface{}, encoder Encoder) ([]byte, error) {
+	if encoder == EncoderGob {
+		var buf bytes.Buffer
+		err := gob.NewEncoder(&buf).Encode(value)
+		return buf.Bytes(), err
+	}
+	return json.Marshal(value)
+}
+
+func decode(data []byte, target interface{}, encoder Encoder) error {
+	if encoder == EncoderGob {
+		return gob.NewDecoder(bytes.NewReader(data)).Decode(target)
+	}
+	return json.Unmarshal(data, target)
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithEncoderJSON(t *testing.T) {
+	source := struct{ Data *SourceTypeA }{&SourceTypeA{Foo: 42, Bar: "Bar"}}
+	dest := struct{ Data []byte }{}
+
+	MapToDestination(&source, &dest, WithEncoder(EncoderJSON))
+	assert.JSONEq(t, `{"Foo":42,"Bar":"Bar"}`, string(dest.Data))
+
+	back := struct{ Data DestTypeA }{}
+	MapToDestination(&dest, &back, WithEncoder(EncoderJSON))
+	assert.Equal(t, DestTypeA{Foo: 42, Bar: "Bar"}, back.Data)
+}
+
+func TestWithEncoderGob(t *testing.T) {
+	source := struct{ Data SourceTypeA }{SourceTypeA{Foo: 42, Bar: "Bar"}}
+	dest := struct{ Data []byte }{}
+
+	MapToDestination(&source, &dest, WithEncoder(EncoderGob))
+	assert.NotEmpty(t, dest.Data)
+
+	back := struct{ Data *DestTypeA }{}
+	MapToDestination(&dest, &back, WithEncoder(EncoderGob))
+	assert.Equal(t, &DestTypeA{Foo: 42, Bar: "Bar"}, back.Data)
+}
+
+func TestWithEncoderNilPointer(t *testing.T) {
+	source := struct{ Data *SourceTypeA }{}
+	dest := struct{ Data []byte }{[]byte("old")}
+
+	MapToDestination(&source, &dest, WithEncoder(EncoderJSON))
+	assert.Nil(t, dest.Data)
+}
+
+func TestWithEncoderReturnsDecodingErrors(t *testing.T) {
+	source := struct{ Data []byte }{[]byte("{")}
+	dest := struct{ Data DestTypeA }{}
+
+	err := NewMapper(WithEncoder(EncoderJSON)).MapDir(&source, &dest, ToDestination)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot decode automapper.DestTypeA")
+}
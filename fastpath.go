@@ -0,0 +1,161 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// fieldStep is a single step of a fieldPlan. A direct step copies the
+// source field at sourceIndex to the destination field at destIndex. Any other
+// step maps the field at index of the type driving the mapping as usual.
+type fieldStep struct {
+	index                  int
+	direct                 bool
+	sourceIndex, destIndex int
+}
+
+// fieldPlan holds the steps that map all fields of a struct type to another.
+type fieldPlan []fieldStep
+
+type fieldPlanKey struct {
+	sourceType, destType reflect.Type
+	useSourceMemberList  bool
+}
+
+// fieldPlans caches the result of newFieldPlan.
+var fieldPlans sync.Map
+
+// allowsFastPath returns true if the options don't change how fields of
+// identical scalar types are mapped, so they can be copied directly.
+func (o mapOptions) allowsFastPath() bool {
+	return !o.tagMatching && len(o.converters) == 0 && len(o.fieldTransforms) == 0 &&
+		len(o.aliases) == 0 && o.ignorePattern == nil && o.mask == nil && o.resolver == nil && o.tagParser == nil &&
+		!o.verifiesFields() && !o.tracesValues()
+}
+
+// tracesValues returns true if every value mapped is seen by mapValues, which
+// counts the depth of the mapping or logs it, so no value may be copied
+// directly.
+func (o mapOptions) tracesValues() bool {
+	return o.maxDepth > 0 || o.logger != nil
+}
+
+// mapFieldsWithPlan maps the fields of two structs like mapFields does, but
+// copies fields of identical scalar types directly, without going through
+// mapValues.
+func mapFieldsWithPlan(sourceVal, destVal reflect.Value, opts mapOptions) {
+	for _, step := range cachedFieldPlan(sourceVal.Type(), destVal.Type(), opts.useSourceMemberList) {
+		switch {
+		case step.direct:
+			destVal.Field(step.destIndex).Set(sourceVal.Field(step.sourceIndex))
+		case opts.useSourceMemberList:
+			mapSourceField(sourceVal, destVal, step.index, opts)
+		default:
+			mapDestField(sourceVal, destVal, step.index, opts)
+		}
+	}
+}
+
+func cachedFieldPlan(sourceType, destType reflect.Type, useSourceMemberList bool) fieldPlan {
+	key := fieldPlanKey{sourceType, destType, useSourceMemberList}
+	if plan, ok := fieldPlans.Load(key); ok {
+		return plan.(fieldPlan)
+	}
+	plan := newFieldPlan(sourceType, destType, useSourceMemberList)
+	fieldPlans.Store(key, plan)
+	return plan
+}
+
+func newFieldPlan(sourceType, destType reflect.Type, useSourceMemberList bool) fieldPlan {
+	drivingType, otherType := destType, sourceType
+	if useSourceMemberList {
+		drivingType, otherType = sourceType, destType
+	}
+	plan := make(fieldPlan, drivingType.NumField())
+	for i := range plan {
+		plan[i].index = i
+		otherIndex, ok := directFieldIndex(drivingType.Field(i), otherType)
+		if !ok {
+			continue
+		}
+		plan[i].direct = true
+		if useSourceMemberList {
+			plan[i].sourceIndex, plan[i].destIndex = i, otherIndex
+		} else {
+			plan[i].sourceIndex, plan[i].destIndex = otherIndex, i
+		}
+	}
+	return plan
+}
+
+// directFieldIndex returns the index of the field of otherType that field
+// maps to, if the two can be copied directly. This is the case for exported
+// fields of the same scalar type that are matched by name only, where the
+// field of otherType is not promoted from an embedded struct.
+func directFieldIndex(field reflect.StructField, otherType reflect.Type) (int, bool) {
+	tag := parseTag(field)
+	if tag.skip || tag.hasOptions || field.Anonymous || field.PkgPath != "" || !isScalarKind(field.Type.Kind()) {
+		return 0, false
+	}
+	otherField, ok := otherType.FieldByName(tag.name)
+	if !ok || len(otherField.Index) != 1 || otherField.PkgPath != "" || otherField.Type != field.Type {
+		return 0, false
+	}
+	return otherField.Index[0], true
+}
+
+func isScalarKind(kind reflect.Kind) bool {
+	return kind == reflect.Bool || kind == reflect.String || isNumericKind(kind) ||
+		kind == reflect.Complex64 || kind == reflect.Complex128
+}
+
+// positionalMatches caches the result of verifyPositionalMatch.
+var positionalMatches sync.Map
+
+// mapFieldsByPosition maps every field of the struct sourceVal to the field of
+// destVal at the same position.
+func mapFieldsByPosition(sourceVal, destVal reflect.Value, opts mapOptions) {
+	sourceType, destType := sourceVal.Type(), destVal.Type()
+	pair := typePair{sourceType, destType}
+	result, ok := positionalMatches.Load(pair)
+	if !ok {
+		result = verifyPositionalMatch(sourceType, destType)
+		positionalMatches.Store(pair, result)
+	}
+	if mismatch := result.(string); mismatch != "" {
+		panic(mismatch)
+	}
+	for i := 0; i < destType.NumField(); i++ {
+		if destType.Field(i).PkgPath != "" {
+			continue
+		}
+		if !opts.tracesValues() && isScalarKind(destType.Field(i).Type.Kind()) && sourceType.Field(i).Type == destType.Field(i).Type {
+			destVal.Field(i).Set(sourceVal.Field(i))
+			continue
+		}
+		fieldOpts := opts
+		fieldOpts.sourcePath = joinPath(opts.sourcePath, sourceType.Field(i).Name)
+		fieldOpts.destPath = joinPath(opts.destPath, destType.Field(i).Name)
+		mapValues(sourceVal.Field(i), destVal.Field(i), fieldOpts)
+	}
+}
+
+// verifyPositionalMatch returns a description of why the fields of the two
+// struct types can't be matched by position, or the empty string if they can.
+func verifyPositionalMatch(sourceType, destType reflect.Type) string {
+	if sourceType.NumField() != destType.NumField() {
+		return fmt.Sprintf("cannot match fields by position: %v has %d fields, %v has %d",
+			sourceType, sourceType.NumField(), destType, destType.NumField())
+	}
+	for i := 0; i < destType.NumField(); i++ {
+		sourceField, destField := sourceType.Field(i), destType.Field(i)
+		if sourceField.Type.Kind() != destField.Type.Kind() || (sourceField.PkgPath == "") != (destField.PkgPath == "") {
+			return fmt.Sprintf("cannot match fields by position: field %d is %s %v in %v, but %s %v in %v",
+				i, sourceField.Name, sourceField.Type, sourceType, destField.Name, destField.Type, destType)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,196 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type flatSource struct {
+	ID      int
+	Name    string
+	Enabled bool
+	Score   float64
+	Child   SourceTypeA
+}
+
+type flatDest struct {
+	ID      int
+	Title   string `automapper:"Name"`
+	Enabled bool
+	Score   float32
+	Child   DestTypeA
+}
+
+func TestFastPathMapsLikeRegularPath(t *testing.T) {
+	source := flatSource{ID: 1, Name: "Name", Enabled: true, Score: 1.5, Child: SourceTypeA{Foo: 42}}
+
+	fast := flatDest{}
+	MapToDestination(&source, &fast)
+	regular := flatDest{}
+	MapToDestination(&source, &regular, WithStrict())
+
+	assert.Equal(t, flatDest{ID: 1, Title: "Name", Enabled: true, Score: 1.5, Child: DestTypeA{Foo: 42}}, fast)
+	assert.Equal(t, regular, fast)
+}
+
+func TestFastPathFromSource(t *testing.T) {
+	source := struct {
+		Foo int
+		Bar string `automapper:"Baz"`
+	}{42, "Bar"}
+	dest := struct {
+		Foo int
+		Baz string
+	}{}
+
+	MapFromSource(&source, &dest)
+	assert.Equal(t, 42, dest.Foo)
+	assert.Equal(t, "Bar", dest.Baz)
+}
+
+func TestFastPathHonorsMaxDepth(t *testing.T) {
+	source := struct {
+		ID   int
+		Name string
+	}{ID: 1}
+	same := struct{ ID int }{}
+	converted := struct{ ID int64 }{}
+	positional := struct {
+		Key   int
+		Title string
+	}{}
+
+	for _, dest := range []interface{}{&same, &converted} {
+		assert.Error(t, NewMapper(WithMaxDepth(1)).MapDir(source, dest, ToDestination))
+		assert.NoError(t, NewMapper(WithMaxDepth(2)).MapDir(source, dest, ToDestination))
+	}
+	assert.Error(t, NewMapper(WithMaxDepth(1), WithPositionalMatch()).MapDir(source, &positional, ToDestination))
+	assert.NoError(t, NewMapper(WithMaxDepth(2), WithPositionalMatch()).MapDir(source, &positional, ToDestination))
+	assert.Equal(t, 1, same.ID)
+	assert.Equal(t, 1, positional.Key)
+}
+
+func TestFieldPlanCopiesOnlyIdenticalScalars(t *testing.T) {
+	plan := newFieldPlan(reflect.TypeOf(flatSource{}), reflect.TypeOf(flatDest{}), false)
+	assert.Equal(t, fieldPlan{
+		{index: 0, direct: true, sourceIndex: 0, destIndex: 0},
+		{index: 1, direct: true, sourceIndex: 1, destIndex: 1},
+		{index: 2, direct: true, sourceIndex: 2, destIndex: 2},
+		{index: 3},
+		{index: 4},
+	}, plan)
+}
+
+func TestSlicesOfIdenticalElementsAreCopied(t *testing.T) {
+	type Items []DestTypeA
+	source := struct {
+		Items []DestTypeA
+		Array [2]DestTypeA
+	}{
+		Items: []DestTypeA{{Foo: 1}, {Foo: 2}},
+		Array: [2]DestTypeA{{Foo: 3}, {Foo: 4}},
+	}
+	dest := struct {
+		Items Items
+		Array []DestTypeA
+	}{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, Items{{Foo: 1}, {Foo: 2}}, dest.Items)
+	assert.Equal(t, []DestTypeA{{Foo: 3}, {Foo: 4}}, dest.Array)
+	dest.Items[0].Foo = 5
+	assert.Equal(t, 1, source.Items[0].Foo)
+}
+
+func TestSlicesOfIdenticalPointersAreCloned(t *testing.T) {
+	type Items []*DestTypeA
+	source := struct{ Items []*DestTypeA }{[]*DestTypeA{{Foo: 1}}}
+	dest := struct{ Items Items }{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, Items{{Foo: 1}}, dest.Items)
+	assert.NotSame(t, source.Items[0], dest.Items[0])
+}
+
+func TestSlicesOfIdenticalElementsWithDeepCopy(t *testing.T) {
+	type Tags [][]string
+	source := struct{ Tags [][]string }{[][]string{{"a"}}}
+	dest := struct{ Tags Tags }{}
+
+	MapToDestination(&source, &dest, WithDeepCopy())
+	dest.Tags[0][0] = "b"
+	assert.Equal(t, "a", source.Tags[0][0])
+}
+
+func TestWithPositionalMatch(t *testing.T) {
+	source := struct {
+		ID    int
+		Name  string
+		Score float64
+		Child SourceTypeA
+	}{1, "name", 1.5, SourceTypeA{Foo: 2, Bar: "bar"}}
+	dest := struct {
+		Key   int
+		Title string `automapper:"Other"`
+		Score float64
+		Child struct {
+			A int
+			B string
+		}
+	}{}
+
+	MapToDestination(&source, &dest, WithPositionalMatch())
+	assert.Equal(t, 1, dest.Key)
+	assert.Equal(t, "name", dest.Title)
+	assert.Equal(t, 1.5, dest.Score)
+	assert.Equal(t, 2, dest.Child.A)
+	assert.Equal(t, "bar", dest.Child.B)
+}
+
+func TestCompileMapperWithPositionalMatchVerifiesKinds(t *testing.T) {
+	_, err := CompileMapper(reflect.TypeOf(struct{ A int }{}), reflect.TypeOf(struct{ B int }{}), WithPositionalMatch())
+	assert.NoError(t, err)
+
+	_, err = CompileMapper(reflect.TypeOf(struct{ A, B int }{}), reflect.TypeOf(struct{ A int }{}), WithPositionalMatch())
+	assert.EqualError(t, err, "cannot map struct { A int; B int } to struct { A int }: "+
+		"cannot match fields by position: struct { A int; B int } has 2 fields, struct { A int } has 1")
+
+	_, err = CompileMapper(reflect.TypeOf(flatSource{}), reflect.TypeOf(flatDest{}), WithPositionalMatch())
+	assert.Error(t, err)
+}
+
+func BenchmarkMapFlatStruct(b *testing.B) {
+	source := flatSource{ID: 1, Name: "Name", Enabled: true, Score: 1.5}
+	dest := flatDest{}
+	for i := 0; i < b.N; i++ {
+		MapToDestination(&source, &dest)
+	}
+}
+
+func BenchmarkMapSliceOfIdenticalElements(b *testing.B) {
+	type Scores []float64
+	source := struct{ Scores []float64 }{make([]float64, 1000)}
+	dest := struct{ Scores Scores }{}
+	for i := 0; i < b.N; i++ {
+		MapToDestination(&source, &dest)
+	}
+}
+
+func BenchmarkMapFlatStructWithPositionalMatch(b *testing.B) {
+	source := flatSource{ID: 1, Name: "Name", Enabled: true, Score: 1.5}
+	dest := struct {
+		Key     int
+		Title   string
+		Enabled bool
+		Score   float64
+		Child   DestTypeA
+	}{}
+	positional := WithPositionalMatch()
+	for i := 0; i < b.N; i++ {
+		MapToDestination(&source, &dest, positional)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// HookPanicError describes a panic raised by a function passed as an option,
+// like a converter or a field transform, that was recovered because of
+// WithRecoverHooks.
+type HookPanicError struct {
+	// Path is the dotted path of the destination field.
+	Path string
+	// Value is the value passed to panic.
+	Value interface{}
+}
+
+func (e *HookPanicError) Error() string {
+	return fmt.Sprintf("hook panicked at %q: %v", e.Path, e.Value)
+}
+
+// hookPanic marks a panic raised by a function passed as an option, so it can
+// be told apart from the panics raised for mapping errors. It reads like the
+// original panic value when it is not recovered.
+type hookPanic struct {
+	value interface{}
+}
+
+func (p *hookPanic) Error() string {
+	return fmt.Sprint(p.value)
+}
+
+func (p *hookPanic) Unwrap() error {
+	err, _ := p.value.(error)
+	return err
+}
+
+// callHook calls fn, marking a panic raised by it as a hookPanic.
+func callHook(fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			panic(&hookPanic{r})
+		}
+	}()
+	fn()
+}
+
+// recoverHook recovers a hookPanic if hooks are recovered, leaving destVal at
+// its zero value and recording the panic as a *HookPanicError. Other panics
+// are passed on. It must be called directly by a deferred statement.
+func recoverHook(destVal reflect.Value, opts mapOptions) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	p, ok := r.(*hookPanic)
+	if !ok {
+		panic(r)
+	}
+	destVal.Set(reflect.Zero(destVal.Type()))
+	if opts.hookErrors != nil {
+		*opts.hookErrors = append(*opts.hookErrors, &HookPanicError{Path: opts.destPath, Value: p.value})
+	}
+}
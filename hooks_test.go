@@ -0,0 +1,93 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithRecoverHooksContinuesPastPanickingConverter(t *testing.T) {
+	source := struct {
+		Foo string
+		Bar string
+	}{"foo", "bar"}
+	dest := struct {
+		Foo []byte
+		Bar string
+	}{}
+	var errs []error
+	panicking := WithConverter(reflect.TypeOf(""), reflect.TypeOf([]byte{}), func(context.Context, interface{}) (interface{}, error) {
+		panic("plugin bug")
+	})
+
+	MapToDestination(&source, &dest, panicking, WithRecoverHooks(&errs))
+	assert.Nil(t, dest.Foo)
+	assert.Equal(t, "bar", dest.Bar)
+	assert.Equal(t, []error{&HookPanicError{Path: "Foo", Value: "plugin bug"}}, errs)
+}
+
+func TestWithRecoverHooksContinuesPastPanickingFieldTransform(t *testing.T) {
+	source := struct{ Foo, Bar string }{"foo", "bar"}
+	dest := struct {
+		Foo string
+		Bar string `automapper:"Bar"`
+	}{}
+	var errs []error
+	panicking := WithFieldTransform("Foo", func(interface{}) interface{} { panic(errors.New("plugin bug")) })
+	upper := WithFieldTransform("Bar", func(v interface{}) interface{} { return strings.ToUpper(v.(string)) })
+
+	MapToDestination(&source, &dest, panicking, upper, WithRecoverHooks(&errs))
+	assert.Equal(t, "", dest.Foo)
+	assert.Equal(t, "BAR", dest.Bar)
+	assert.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], `hook panicked at "Foo": plugin bug`)
+}
+
+func TestWithRecoverHooksContinuesPastPanickingResolver(t *testing.T) {
+	source := struct{ Foo, Bar string }{"foo", "bar"}
+	dest := struct {
+		Foo string
+		Bar string `automapper:"Bar"`
+	}{"old", "old"}
+	var errs []error
+	resolver := WithResolver(func(field reflect.StructField, source reflect.Value) (reflect.Value, bool) {
+		if field.Name == "Foo" {
+			panic("plugin bug")
+		}
+		return reflect.Value{}, false
+	})
+
+	MapToDestination(&source, &dest, resolver, WithRecoverHooks(&errs))
+	assert.Equal(t, "", dest.Foo)
+	assert.Equal(t, "bar", dest.Bar)
+	assert.Equal(t, []error{&HookPanicError{Path: "Foo", Value: "plugin bug"}}, errs)
+}
+
+func TestPanickingConverterAbortsMappingByDefault(t *testing.T) {
+	source := struct{ Foo string }{"foo"}
+	dest := struct{ Foo []byte }{}
+	panicking := WithConverter(reflect.TypeOf(""), reflect.TypeOf([]byte{}), func(context.Context, interface{}) (interface{}, error) {
+		panic("plugin bug")
+	})
+
+	err := NewMapper(panicking).MapDir(&source, &dest, ToDestination)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Error: plugin bug")
+}
+
+func TestWithRecoverHooksStillAbortsOnConverterErrors(t *testing.T) {
+	source := struct{ Foo string }{"foo"}
+	dest := struct{ Foo []byte }{}
+	failing := WithConverter(reflect.TypeOf(""), reflect.TypeOf([]byte{}), func(context.Context, interface{}) (interface{}, error) {
+		return nil, errors.New("invalid")
+	})
+
+	err := NewMapper(failing, WithRecoverHooks(nil)).MapDir(&source, &dest, ToDestination)
+	assert.Error(t, err)
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// decodeJSON unmarshals the JSON held by the byte slice sourceVal into
+// destVal. Decoding errors cause a panic.
+func decodeJSON(sourceVal, destVal reflect.Value) {
+	data := sourceVal.Bytes()
+	if len(data) == 0 {
+		return
+	}
+	target := reflect.New(destVal.Type())
+	if err := json.Unmarshal(data, target.Interface()); err != nil {
+		panic(err)
+	}
+	destVal.Set(target.Elem())
+}
+
+// jsonFieldName returns the name of the field of struct type t that the JSON
+// object key maps to. Like encoding/json, a field is matched by the name in
+// its json tag, or by its own name if the tag gives none, and fields tagged
+// "-" are never matched. Fields of embedded structs are matched as well.
+func jsonFieldName(t reflect.Type, key string) (string, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" && derefType(field.Type).Kind() == reflect.Struct {
+			if embedded, ok := jsonFieldName(derefType(field.Type), key); ok {
+				return embedded, true
+			}
+			continue
+		}
+		if name == key || name == "" && field.Name == key {
+			return field.Name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithJSONDecode(t *testing.T) {
+	source := struct {
+		Payload json.RawMessage
+		Pointer []byte
+		Empty   json.RawMessage
+	}{
+		Payload: json.RawMessage(`{"Foo": 42, "Bar": "Bar"}`),
+		Pointer: []byte(`{"Foo": 43}`),
+	}
+	dest := struct {
+		Payload DestTypeA
+		Pointer *DestTypeA
+		Empty   *DestTypeA
+	}{}
+
+	MapToDestination(&source, &dest, WithJSONDecode())
+	assert.Equal(t, DestTypeA{Foo: 42, Bar: "Bar"}, dest.Payload)
+	assert.Equal(t, &DestTypeA{Foo: 43}, dest.Pointer)
+	assert.Nil(t, dest.Empty)
+}
+
+func TestWithJSONDecodeInvalidJSON(t *testing.T) {
+	source := struct {
+		Payload json.RawMessage
+	}{json.RawMessage(`{`)}
+	dest := struct {
+		Payload DestTypeA
+	}{}
+
+	err := NewMapper(WithJSONDecode()).MapDir(&source, &dest, ToDestination)
+	assert.Error(t, err)
+}
+
+func TestMapFromSourceMapJSON(t *testing.T) {
+	type Address struct {
+		Street string `json:"street_name"`
+		City   string
+	}
+	type Audit struct {
+		CreatedBy string `json:"created_by"`
+	}
+	type User struct {
+		Audit
+		UserName string    `json:"user_name,omitempty"`
+		Address  *Address  `json:"address"`
+		Roles    []Address `json:"roles"`
+	}
+	var source map[string]interface{}
+	err := json.Unmarshal([]byte(`{
+		"user_name": "john",
+		"created_by": "admin",
+		"address": {"street_name": "Main", "City": "Springfield"},
+		"roles": [{"street_name": "Side"}]
+	}`), &source)
+	assert.NoError(t, err)
+	dest := User{}
+
+	MapFromSourceMapJSON(source, &dest)
+	assert.Equal(t, User{
+		Audit:    Audit{CreatedBy: "admin"},
+		UserName: "john",
+		Address:  &Address{Street: "Main", City: "Springfield"},
+		Roles:    []Address{{Street: "Side"}},
+	}, dest)
+}
+
+func TestMapFromSourceMapJSONAllocatesNilEmbeddedPointers(t *testing.T) {
+	type Audit struct {
+		CreatedBy string `json:"created_by"`
+	}
+	dest := struct {
+		*Audit
+		Name string
+	}{}
+
+	MapFromSourceMapJSON(map[string]interface{}{"created_by": "admin", "Name": "foo"}, &dest)
+	assert.Equal(t, &Audit{CreatedBy: "admin"}, dest.Audit)
+	assert.Equal(t, "foo", dest.Name)
+}
+
+func TestMapFromSourceMapJSONPanicsForUnknownKeys(t *testing.T) {
+	defer func() {
+		assert.Contains(t, recover(), "no destination field 'Secret'")
+	}()
+	dest := struct {
+		Secret string `json:"-"`
+	}{}
+	MapFromSourceMapJSON(map[string]interface{}{"Secret": "foo"}, &dest)
+	t.Error("Should have panicked")
+}
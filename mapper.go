@@ -0,0 +1,114 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Direction selects which of the two types drives a mapping.
+type Direction int
+
+const (
+	// ToDestination maps every field of the destination from the source. This
+	// is the strategy used by MapToDestination.
+	ToDestination Direction = iota
+	// FromSource maps every field of the source into the destination. This is
+	// the strategy used by MapFromSource.
+	FromSource
+)
+
+// Mapper holds a set of options that are applied to every mapping it
+// performs. Unlike the package level functions, the methods on Mapper return
+// an error instead of panicking when the types cannot be mapped.
+type Mapper struct {
+	opts []Option
+}
+
+// NewMapper creates a Mapper that applies opts to every mapping.
+func NewMapper(opts ...Option) *Mapper {
+	return &Mapper{opts: opts}
+}
+
+// MapDir fills out the fields in dest with values from source, using the
+// member list selected by dir. As automapper tags are always read from the
+// type driving the mapping, a single set of tags on one type can be used to
+// map in both directions:
+//
+//	m.MapDir(a, &b, ToDestination) // tags on b name the fields of a
+//	m.MapDir(b, &a, FromSource)    // the same tags now name the fields of a
+func (m *Mapper) MapDir(source, dest interface{}, dir Direction) (err error) {
+	defer recoverError(&err)
+	mapTopLevel(source, dest, newMapOptions(dir == FromSource, m.opts))
+	return nil
+}
+
+// MapContext works like MapDir, but passes ctx to the converters, so they can
+// use request scoped data, e.g. a locale or the configuration of a tenant. Like
+// MapToDestinationContext, it stops mapping as soon as ctx is done.
+func (m *Mapper) MapContext(ctx context.Context, source, dest interface{}, dir Direction) (err error) {
+	defer recoverError(&err)
+	options := newMapOptions(dir == FromSource, m.opts)
+	options.ctx = ctx
+	mapTopLevel(source, dest, options)
+	return nil
+}
+
+// MapAll maps the elements of the slice or array source into a new slice
+// stored in dest, which must be a pointer to a slice. Every element is mapped
+// on its own, like MapDir with ToDestination does, so a failing element does
+// not abort the others but is left at its zero value. If any element fails,
+// the returned slice holds the error of every element at its index, and nil
+// for the elements that were mapped. Otherwise MapAll returns nil. It panics
+// if source is not a slice or an array, or dest not a pointer to a slice.
+func (m *Mapper) MapAll(source, dest interface{}) []error {
+	sourceVal := reflect.Indirect(reflect.ValueOf(source))
+	if sourceVal.Kind() != reflect.Slice && sourceVal.Kind() != reflect.Array {
+		panic(fmt.Sprintf("MapAll requires a slice or array source, got %T", source))
+	}
+	destVal := destinationValue(dest)
+	if destVal.Kind() != reflect.Slice {
+		panic(fmt.Sprintf("MapAll requires a pointer to a slice as dest, got %T", dest))
+	}
+	options := newMapOptions(false, m.opts)
+	target := reflect.MakeSlice(destVal.Type(), sourceVal.Len(), sourceVal.Len())
+	errs := make([]error, sourceVal.Len())
+	failed := false
+	for i := range errs {
+		errs[i] = mapElement(sourceVal.Index(i), target.Index(i), options)
+		failed = failed || errs[i] != nil
+	}
+	destVal.Set(target)
+	if !failed {
+		return nil
+	}
+	return errs
+}
+
+// mapElement maps a single element for MapAll. destVal is only set if the
+// element is mapped without errors.
+func mapElement(sourceVal, destVal reflect.Value, opts mapOptions) (err error) {
+	defer recoverError(&err)
+	elem := reflect.New(destVal.Type()).Elem()
+	mapRootValues(sourceVal, elem, opts)
+	destVal.Set(elem)
+	return nil
+}
+
+// recoverError turns a panic raised during mapping into an error stored in
+// err. It must be called directly by a deferred statement.
+func recoverError(err *error) {
+	if r := recover(); r != nil {
+		*err = errorFromPanic(r)
+	}
+}
+
+// errorFromPanic returns the value passed to panic as an error.
+func errorFromPanic(r interface{}) error {
+	if err, ok := r.(error); ok {
+		return err
+	}
+	return fmt.Errorf("%v", r)
+}
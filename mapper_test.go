@@ -0,0 +1,109 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapDirUsesTheSameTagsInBothDirections(t *testing.T) {
+	type Domain struct {
+		Foo string
+	}
+	type DTO struct {
+		Bar string `automapper:"Foo"`
+	}
+	mapper := NewMapper()
+
+	dto := DTO{}
+	err := mapper.MapDir(Domain{Foo: "abc"}, &dto, ToDestination)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", dto.Bar)
+
+	domain := Domain{}
+	err = mapper.MapDir(DTO{Bar: "def"}, &domain, FromSource)
+	assert.NoError(t, err)
+	assert.Equal(t, "def", domain.Foo)
+}
+
+func TestMapDirReturnsError(t *testing.T) {
+	source := struct{ Foo string }{}
+	dest := struct{ Foo, Bar string }{}
+	err := NewMapper().MapDir(&source, &dest, ToDestination)
+	assert.Error(t, err)
+}
+
+func TestMapDirAppliesOptions(t *testing.T) {
+	source := struct{ Foo int64 }{1 << 40}
+	dest := struct{ Foo int32 }{}
+	err := NewMapper(WithOverflowCheck()).MapDir(&source, &dest, ToDestination)
+	assert.Error(t, err)
+}
+
+type localeKey struct{}
+
+func TestMapContextPassesContextToConverters(t *testing.T) {
+	format := WithConverter(reflect.TypeOf(0.0), reflect.TypeOf(""), func(ctx context.Context, source interface{}) (interface{}, error) {
+		if ctx.Value(localeKey{}) == "nl" {
+			return strings.Replace(fmt.Sprintf("%.2f", source), ".", ",", 1), nil
+		}
+		return fmt.Sprintf("%.2f", source), nil
+	})
+	mapper := NewMapper(format)
+	source := struct{ Price float64 }{1.5}
+	dest := struct{ Price string }{}
+
+	ctx := context.WithValue(context.Background(), localeKey{}, "nl")
+	assert.NoError(t, mapper.MapContext(ctx, &source, &dest, ToDestination))
+	assert.Equal(t, "1,50", dest.Price)
+
+	assert.NoError(t, mapper.MapDir(&source, &dest, ToDestination))
+	assert.Equal(t, "1.50", dest.Price)
+}
+
+func TestMapContextStopsWhenContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := NewMapper().MapContext(ctx, &SourceTypeA{}, &DestTypeA{}, ToDestination)
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestMapAll(t *testing.T) {
+	source := []struct{ Foo string }{{"1"}, {"x"}, {"3"}}
+	var dest []struct{ Foo int }
+	atoi := WithConverter(reflect.TypeOf(""), reflect.TypeOf(0), func(_ context.Context, source interface{}) (interface{}, error) {
+		return strconv.Atoi(source.(string))
+	})
+
+	errs := NewMapper(atoi).MapAll(source, &dest)
+	assert.Equal(t, []struct{ Foo int }{{1}, {0}, {3}}, dest)
+	if assert.Len(t, errs, 3) {
+		assert.NoError(t, errs[0])
+		assert.Error(t, errs[1])
+		assert.NoError(t, errs[2])
+	}
+}
+
+func TestMapAllReturnsNilWithoutErrors(t *testing.T) {
+	source := [2]SourceTypeA{{Foo: 1}, {Foo: 2}}
+	var dest []*DestTypeA
+
+	errs := NewMapper().MapAll(&source, &dest)
+	assert.Nil(t, errs)
+	assert.Equal(t, []*DestTypeA{{Foo: 1}, {Foo: 2}}, dest)
+}
+
+func TestMapAllRequiresSlices(t *testing.T) {
+	defer func() { recover() }()
+	var dest []DestTypeA
+	NewMapper().MapAll(SourceTypeA{}, &dest)
+	t.Error("Should have panicked")
+}
@@ -0,0 +1,270 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// MapToMap fills out dest with the fields of source, which must be a struct or
+// a pointer to a struct. Every exported field is stored under its name, or the
+// name given in its automapper tag. Fields of embedded structs are stored as
+// if they were fields of source itself. Nested structs are stored as nested
+// maps, and slices of structs as []interface{} holding maps. Structs without
+// exported fields, e.g. time.Time, are stored as they are.
+func MapToMap(source interface{}, dest map[string]interface{}, opts ...Option) {
+	var sourceVal = reflect.ValueOf(source)
+	var options = newMapOptions(true, opts)
+	for sourceVal.Kind() == reflect.Ptr {
+		if sourceVal.IsNil() {
+			return
+		}
+		sourceVal = sourceVal.Elem()
+	}
+	if sourceVal.Kind() != reflect.Struct {
+		panic("Source must be a struct or a pointer to a struct")
+	}
+	fillMap(sourceVal, dest, options)
+}
+
+func fillMap(sourceVal reflect.Value, dest map[string]interface{}, opts mapOptions) {
+	sourceType := sourceVal.Type()
+	written := map[string]bool{}
+	var embedded []reflect.Value
+	for i := 0; i < sourceType.NumField(); i++ {
+		field := sourceType.Field(i)
+		tag := opts.parseTag(field)
+		if tag.skip {
+			continue
+		}
+		fieldVal := sourceVal.Field(i)
+		if field.Anonymous {
+			if embeddedVal, ok := derefValue(fieldVal); ok && embeddedVal.Kind() == reflect.Struct {
+				embedded = append(embedded, embeddedVal)
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if tag.omitEmpty && opts.isZero(fieldVal) {
+			continue
+		}
+		key := mapKey(tag.name, opts)
+		dest[key] = mapEntryValue(fieldVal, opts)
+		written[key] = true
+	}
+	// Like promoted fields in Go, the fields of embedded structs never
+	// replace the fields of the outer struct.
+	for _, embeddedVal := range embedded {
+		promoted := map[string]interface{}{}
+		fillMap(embeddedVal, promoted, opts)
+		for key, value := range promoted {
+			if !written[key] {
+				dest[key] = value
+			}
+		}
+	}
+}
+
+// mapEntryValue returns the value to store in a map produced by MapToMap.
+func mapEntryValue(value reflect.Value, opts mapOptions) interface{} {
+	switch {
+	case value.Kind() == reflect.Ptr && value.IsNil():
+		return nil
+	case isMappableStruct(derefType(value.Type())):
+		result := map[string]interface{}{}
+		elem, _ := derefValue(value)
+		fillMap(elem, result, opts)
+		return result
+	case value.Kind() == reflect.Slice && isMappableStruct(derefType(value.Type().Elem())):
+		if value.IsNil() {
+			return []interface{}(nil)
+		}
+		result := make([]interface{}, value.Len())
+		for i := range result {
+			result[i] = mapEntryValue(value.Index(i), opts)
+		}
+		return result
+	}
+	return value.Interface()
+}
+
+// isMappableStruct returns true for struct types that MapToMap turns into a
+// map, which are structs with at least one exported field.
+func isMappableStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && len(exportedFieldNames(t)) > 0
+}
+
+func mapKey(fieldName string, opts mapOptions) string {
+	if opts.keyNamer != nil {
+		return opts.keyNamer(fieldName)
+	}
+	return fieldName
+}
+
+// derefValue follows pointers until reaching a non-pointer value. ok is false
+// if a nil pointer is encountered.
+func derefValue(value reflect.Value) (result reflect.Value, ok bool) {
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return value, false
+		}
+		value = value.Elem()
+	}
+	return value, true
+}
+
+// isStringMap returns true for map types with string keys.
+func isStringMap(t reflect.Type) bool {
+	return t.Kind() == reflect.Map && t.Key().Kind() == reflect.String
+}
+
+var interfaceMapType = reflect.TypeOf(map[string]interface{}{})
+
+// isInterfaceMap returns true for map[string]interface{} and the named types
+// defined as it, which are the maps that MapToMap fills.
+func isInterfaceMap(t reflect.Type) bool {
+	return t.Kind() == reflect.Map && t.ConvertibleTo(interfaceMapType)
+}
+
+// mapStructToMap fills the map destVal with the fields of the struct
+// sourceVal, like MapToMap. A nil map is allocated first.
+func mapStructToMap(sourceVal, destVal reflect.Value, opts mapOptions) {
+	if destVal.IsNil() {
+		destVal.Set(reflect.MakeMap(destVal.Type()))
+	}
+	fillMap(sourceVal, destVal.Convert(interfaceMapType).Interface().(map[string]interface{}), opts)
+}
+
+// mapStringMapToStruct maps every entry of the map sourceVal into the field
+// of the struct destVal named by its key.
+func mapStringMapToStruct(sourceVal, destVal reflect.Value, opts mapOptions) {
+	iter := sourceVal.MapRange()
+	for iter.Next() {
+		key := iter.Key().String()
+		name, ok := key, true
+		if opts.keyNormalizer != nil {
+			name = opts.keyNormalizer(key)
+		}
+		if opts.jsonKeys {
+			name, ok = jsonFieldName(destVal.Type(), name)
+		}
+		var destFieldVal reflect.Value
+		if ok {
+			// Nil embedded pointers are allocated for promoted fields.
+			destFieldVal = allocatedFieldByName(destVal, name)
+		}
+		if !destFieldVal.IsValid() {
+			panic(fmt.Sprintf("no destination field '%s' in %v", key, destVal.Type()))
+		}
+		fieldOpts := opts
+		fieldOpts.sourcePath = keyPath(opts.sourcePath, iter.Key())
+		fieldOpts.destPath = fieldPath(opts.destPath, destVal.Type(), name)
+		mapValues(iter.Value(), destFieldVal, fieldOpts)
+	}
+}
+
+// mapMap maps the keys and values of the map sourceVal into a new map of the
+// type of destVal. Keys are mapped like any other value, so their types may
+// differ as long as they are compatible. Named map types map like the map
+// types they are defined as, as the new map always has the type of destVal.
+func mapMap(sourceVal, destVal reflect.Value, opts mapOptions) {
+	if sourceVal.IsNil() {
+		destVal.Set(reflect.Zero(destVal.Type()))
+		return
+	}
+	destType := destVal.Type()
+	boxed := opts.boxesMapValues(sourceVal.Type().Elem(), destType.Elem())
+	target := reflect.MakeMapWithSize(destType, sourceVal.Len())
+	iter := sourceVal.MapRange()
+	for iter.Next() {
+		key := mapKeyValue(iter.Key(), destType.Key(), opts)
+		elemOpts := opts
+		elemOpts.sourcePath = keyPath(opts.sourcePath, iter.Key())
+		elemOpts.destPath = keyPath(opts.destPath, key)
+		val := reflect.New(destType.Elem()).Elem()
+		if boxed {
+			// Typed values are boxed as copies, so the destination map
+			// shares no pointers, slices or maps with the source.
+			val.Set(deepCopy(iter.Value(), elemOpts))
+		} else {
+			mapValues(iter.Value(), val, elemOpts)
+		}
+		target.SetMapIndex(key, val)
+	}
+	destVal.Set(target)
+}
+
+// boxesMapValues returns true if map values of the concrete type sourceType
+// are stored into the interface type destType as they are, rather than being
+// mapped.
+func (o mapOptions) boxesMapValues(sourceType, destType reflect.Type) bool {
+	if destType.Kind() != reflect.Interface || sourceType.Kind() == reflect.Interface || !sourceType.Implements(destType) {
+		return false
+	}
+	_, hasConverter := o.converter(sourceType, destType)
+	return !hasConverter
+}
+
+// mapMapToSlice maps the values of the map sourceVal into the slice destVal,
+// ordered by their keys. A nil map maps like a nil slice.
+func mapMapToSlice(sourceVal, destVal reflect.Value, opts mapOptions) {
+	valuesType := reflect.SliceOf(sourceVal.Type().Elem())
+	if sourceVal.IsNil() {
+		mapValues(reflect.Zero(valuesType), destVal, opts)
+		return
+	}
+	keys := sortedKeys(sourceVal, opts)
+	values := reflect.MakeSlice(valuesType, len(keys), len(keys))
+	for i, key := range keys {
+		values.Index(i).Set(sourceVal.MapIndex(key))
+	}
+	mapValues(values, destVal, opts)
+}
+
+// sortedKeys returns the keys of the map sourceVal, sorted by the comparator
+// given with WithKeyLess, or else in their natural order. Only numbers and
+// strings have a natural order.
+func sortedKeys(sourceVal reflect.Value, opts mapOptions) []reflect.Value {
+	keys := sourceVal.MapKeys()
+	var less func(a, b reflect.Value) bool
+	switch kind := sourceVal.Type().Key().Kind(); {
+	case opts.keyLess != nil:
+		less = func(a, b reflect.Value) bool { return opts.keyLess(a.Interface(), b.Interface()) }
+	case isIntKind(kind):
+		less = func(a, b reflect.Value) bool { return a.Int() < b.Int() }
+	case isUintKind(kind):
+		less = func(a, b reflect.Value) bool { return a.Uint() < b.Uint() }
+	case isFloatKind(kind):
+		less = func(a, b reflect.Value) bool { return a.Float() < b.Float() }
+	case kind == reflect.String:
+		less = func(a, b reflect.Value) bool { return a.String() < b.String() }
+	default:
+		panic(fmt.Sprintf("cannot sort map keys of type %v; use WithKeyLess to order them", sourceVal.Type().Key()))
+	}
+	sort.Slice(keys, func(i, j int) bool { return less(keys[i], keys[j]) })
+	return keys
+}
+
+// mapKeyValue maps a source map key to the key type of the destination map.
+func mapKeyValue(sourceKey reflect.Value, keyType reflect.Type, opts mapOptions) (key reflect.Value) {
+	if sourceKey.Type() == keyType {
+		return sourceKey
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			panic(fmt.Sprintf("cannot map key %v of type %v to %v: %v", sourceKey, sourceKey.Type(), keyType, r))
+		}
+	}()
+	key = reflect.New(keyType).Elem()
+	mapValues(sourceKey, key, opts)
+	return key
+}
+
+// keyPath appends a map key to a field path.
+func keyPath(prefix string, key reflect.Value) string {
+	return fmt.Sprintf("%s[%v]", prefix, key)
+}
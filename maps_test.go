@@ -0,0 +1,334 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapToMap(t *testing.T) {
+	now := time.Now()
+	source := struct {
+		Foo      string
+		Bar      int    `automapper:"Baz"`
+		Skipped  string `automapper:"-"`
+		Child    SourceTypeA
+		Nil      *SourceTypeA
+		Children []SourceTypeA
+		Time     time.Time
+		SourceTypeA
+	}{
+		Foo:         "abc",
+		Bar:         42,
+		Child:       SourceTypeA{Foo: 1, Bar: "1"},
+		Children:    []SourceTypeA{{Foo: 2, Bar: "2"}},
+		Time:        now,
+		SourceTypeA: SourceTypeA{Foo: 3, Bar: "3"},
+	}
+	dest := map[string]interface{}{}
+
+	MapToMap(&source, dest)
+	assert.Equal(t, map[string]interface{}{
+		"Foo":      "abc",
+		"Baz":      42,
+		"Child":    map[string]interface{}{"Foo": 1, "Bar": "1"},
+		"Nil":      nil,
+		"Children": []interface{}{map[string]interface{}{"Foo": 2, "Bar": "2"}},
+		"Time":     now,
+		"Bar":      "3",
+	}, dest)
+}
+
+func TestMapToDestinationWithMapDestination(t *testing.T) {
+	type Attributes map[string]interface{}
+	source := struct {
+		Foo   string
+		Child SourceTypeA
+	}{"abc", SourceTypeA{Foo: 1, Bar: "1"}}
+	dest := map[string]interface{}{"Existing": true}
+	var attributes Attributes
+
+	MapToDestination(&source, &dest)
+	MapToDestination(source, &attributes)
+	assert.Equal(t, map[string]interface{}{
+		"Existing": true,
+		"Foo":      "abc",
+		"Child":    map[string]interface{}{"Foo": 1, "Bar": "1"},
+	}, dest)
+	assert.Equal(t, Attributes{
+		"Foo":   "abc",
+		"Child": map[string]interface{}{"Foo": 1, "Bar": "1"},
+	}, attributes)
+}
+
+func TestMapStructFieldToInterfaceMapField(t *testing.T) {
+	type Metadata struct {
+		Source string
+		Child  SourceTypeA
+	}
+	source := struct {
+		Metadata Metadata
+		Extra    *Metadata
+	}{
+		Metadata: Metadata{"api", SourceTypeA{Foo: 1, Bar: "1"}},
+		Extra:    &Metadata{Source: "batch"},
+	}
+	dest := struct {
+		Metadata map[string]interface{}
+		Extra    map[string]interface{}
+	}{Metadata: map[string]interface{}{"Stale": true}}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, map[string]interface{}{
+		"Source": "api",
+		"Child":  map[string]interface{}{"Foo": 1, "Bar": "1"},
+	}, dest.Metadata)
+	assert.Equal(t, "batch", dest.Extra["Source"])
+}
+
+func TestMapToMapWithKeyNamer(t *testing.T) {
+	source := struct {
+		UserName string
+		Address  struct {
+			StreetName string
+		}
+	}{UserName: "abc"}
+	source.Address.StreetName = "def"
+	dest := map[string]interface{}{}
+
+	MapToMap(source, dest, WithKeyNamer(toSnakeCase))
+	assert.Equal(t, map[string]interface{}{
+		"user_name": "abc",
+		"address":   map[string]interface{}{"street_name": "def"},
+	}, dest)
+}
+
+func toSnakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func TestMapMapWithIntKeys(t *testing.T) {
+	source := struct {
+		Entries map[int]SourceTypeA
+	}{map[int]SourceTypeA{1: {Foo: 1}, 2: {Foo: 2}}}
+	dest := struct {
+		Entries map[int]DestTypeA
+	}{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, map[int]DestTypeA{1: {Foo: 1}, 2: {Foo: 2}}, dest.Entries)
+}
+
+func TestMapMapWithConvertibleKeys(t *testing.T) {
+	type ID int64
+	type Key struct{ A, B int }
+	type KeyDTO struct{ A, B int64 }
+	source := struct {
+		Named  map[int]string
+		Struct map[Key]int
+	}{
+		Named:  map[int]string{1: "a"},
+		Struct: map[Key]int{{1, 2}: 3},
+	}
+	dest := struct {
+		Named  map[ID]string
+		Struct map[KeyDTO]int
+	}{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, map[ID]string{1: "a"}, dest.Named)
+	assert.Equal(t, map[KeyDTO]int{{1, 2}: 3}, dest.Struct)
+}
+
+func TestMapNamedMapTypes(t *testing.T) {
+	type Headers map[string]string
+	type Labels map[string]string
+	source := struct {
+		Headers Headers
+		Plain   map[string]string
+		Labels  Headers
+	}{
+		Headers: Headers{"Accept": "text/plain"},
+		Plain:   map[string]string{"Host": "example.com"},
+		Labels:  Headers{"app": "web"},
+	}
+	dest := struct {
+		Headers map[string]string
+		Plain   Headers
+		Labels  Labels
+	}{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, map[string]string{"Accept": "text/plain"}, dest.Headers)
+	assert.Equal(t, Headers{"Host": "example.com"}, dest.Plain)
+	assert.Equal(t, Labels{"app": "web"}, dest.Labels)
+}
+
+func TestMapNamedMapTypeDoesNotShareTheSourceMap(t *testing.T) {
+	type Headers map[string]string
+	source := struct{ Headers Headers }{Headers{"Accept": "text/plain"}}
+	dest := struct{ Headers map[string]string }{}
+
+	MapToDestination(&source, &dest)
+	dest.Headers["Accept"] = "application/json"
+	assert.Equal(t, "text/plain", source.Headers["Accept"])
+}
+
+func TestMapMapWithTypedValuesToInterfaceValues(t *testing.T) {
+	type Item struct {
+		Name string
+		Tags []string
+	}
+	source := struct {
+		Items map[string]Item
+		Refs  map[string]*Item
+	}{
+		Items: map[string]Item{"a": {"foo", []string{"x"}}},
+		Refs:  map[string]*Item{"b": {Name: "bar"}},
+	}
+	dest := struct {
+		Items map[string]interface{}
+		Refs  map[string]interface{}
+	}{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, map[string]interface{}{"a": Item{"foo", []string{"x"}}}, dest.Items)
+	assert.Equal(t, map[string]interface{}{"b": &Item{Name: "bar"}}, dest.Refs)
+	dest.Items["a"].(Item).Tags[0] = "y"
+	dest.Refs["b"].(*Item).Name = "baz"
+	assert.Equal(t, "x", source.Items["a"].Tags[0])
+	assert.Equal(t, "bar", source.Refs["b"].Name)
+}
+
+func TestMapPointerToInterfaceFieldKeepsIdentity(t *testing.T) {
+	logger := &strings.Builder{}
+	source := struct{ Out *strings.Builder }{logger}
+	dest := struct{ Out fmt.Stringer }{}
+
+	MapToDestination(&source, &dest)
+	assert.Same(t, logger, dest.Out)
+}
+
+func TestMapMapWithNilMap(t *testing.T) {
+	source := struct {
+		Entries map[int]SourceTypeA
+	}{}
+	dest := struct {
+		Entries map[int]DestTypeA
+	}{map[int]DestTypeA{1: {}}}
+
+	MapToDestination(&source, &dest)
+	assert.Nil(t, dest.Entries)
+}
+
+func TestMapMapWithIncompatibleKeys(t *testing.T) {
+	source := struct {
+		Entries map[string]int
+	}{map[string]int{"a": 1}}
+	dest := struct {
+		Entries map[struct{ A int }]int
+	}{}
+
+	err := NewMapper().MapDir(&source, &dest, ToDestination)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot map key a of type string")
+}
+
+func TestMapMapToSliceOrderedByKey(t *testing.T) {
+	source := struct{ Items map[int]SourceTypeA }{map[int]SourceTypeA{
+		10: {Foo: 10}, 2: {Foo: 2}, -1: {Foo: -1},
+	}}
+	dest := struct{ Items []DestTypeA }{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, []DestTypeA{{Foo: -1}, {Foo: 2}, {Foo: 10}}, dest.Items)
+}
+
+func TestMapMapWithStringKeysToSlice(t *testing.T) {
+	source := struct{ Items map[string]int }{map[string]int{"b": 2, "a": 1, "c": 3}}
+	dest := struct{ Items []int64 }{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, []int64{1, 2, 3}, dest.Items)
+
+	source.Items = nil
+	MapToDestination(&source, &dest)
+	assert.Nil(t, dest.Items)
+}
+
+func TestMapMapToSliceWithKeyLess(t *testing.T) {
+	source := struct{ Items map[string]int }{map[string]int{"b": 2, "a": 1, "c": 3}}
+	dest := struct{ Items []int }{}
+	descending := WithKeyLess(func(a, b interface{}) bool {
+		return a.(string) > b.(string)
+	})
+
+	MapToDestination(&source, &dest, descending)
+	assert.Equal(t, []int{3, 2, 1}, dest.Items)
+}
+
+func TestMapMapWithUnorderedKeysToSlicePanics(t *testing.T) {
+	defer func() {
+		r := recover()
+		assert.Contains(t, r, "cannot sort map keys of type struct")
+	}()
+	source := struct{ Items map[struct{ ID int }]int }{map[struct{ ID int }]int{{1}: 1}}
+	dest := struct{ Items []int }{}
+
+	MapToDestination(&source, &dest)
+	t.Error("Should have panicked")
+}
+
+func exportedKey(key string) string {
+	key = strings.TrimSpace(key)
+	return strings.ToUpper(key[:1]) + key[1:]
+}
+
+func TestMapFromSourceMapWithKeyNormalizer(t *testing.T) {
+	source := map[string]interface{}{
+		" name ": "foo",
+		"owner":  map[string]interface{}{"name": "bar"},
+	}
+	dest := struct {
+		Name  string
+		Owner struct{ Name string }
+	}{}
+
+	MapFromSourceMap(source, &dest, WithKeyNormalizer(exportedKey))
+	assert.Equal(t, "foo", dest.Name)
+	assert.Equal(t, "bar", dest.Owner.Name)
+}
+
+func TestMapFromSourceMapJSONWithKeyNormalizer(t *testing.T) {
+	source := map[string]interface{}{"User_Name": "foo"}
+	dest := struct {
+		Name string `json:"user_name"`
+	}{}
+
+	MapFromSourceMapJSON(source, &dest, WithKeyNormalizer(strings.ToLower))
+	assert.Equal(t, "foo", dest.Name)
+}
+
+func TestMapFromSourceMapWithKeyNormalizerReportsOriginalKey(t *testing.T) {
+	source := map[string]interface{}{"title": "foo"}
+	dest := struct{ Name string }{}
+
+	err := NewMapper(WithKeyNormalizer(exportedKey)).MapDir(source, &dest, ToDestination)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no destination field 'title'")
+}
@@ -0,0 +1,587 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"time"
+)
+
+// Option changes the default behavior of a mapping. Options are passed as
+// trailing arguments to the mapping functions.
+type Option func(*mapOptions)
+
+// defaultStrictMaxDepth is the maximum depth of strict mappings, unless
+// another depth is given with WithMaxDepth.
+const defaultStrictMaxDepth = 32
+
+type mapOptions struct {
+	useSourceMemberList      bool
+	overflowCheck            bool
+	preserveSliceElements    bool
+	reusePointers            bool
+	reuseNestedPointers      bool
+	ctx                      context.Context
+	strict                   bool
+	tagMatching              bool
+	converters               map[typePair]converter
+	keyNamer                 func(fieldName string) string
+	keyNormalizer            func(key string) string
+	jsonDecode               bool
+	fieldTransforms          map[string]func(interface{}) interface{}
+	nilSlicesAsEmpty         bool
+	defaultOnConversionError bool
+	conversionWarnings       *[]error
+	sliceFilter              func(interface{}) bool
+	validate                 bool
+	wrapSingleElements       bool
+	wrapScalars              bool
+	maxDepth                 int
+	depth                    int
+	getters                  bool
+	setters                  bool
+	aliases                  map[string]string
+	sharedPointers           bool
+	resizeArrays             bool
+	logger                   func(path, msg string)
+	isZeroFuncs              map[reflect.Type]func(interface{}) bool
+	ignorePattern            *regexp.Regexp
+	strictTypes              bool
+	prefixes, suffixes       []string
+	unsafeUnexported         bool
+	mask                     map[string]bool
+	keyLess                  func(a, b interface{}) bool
+	timeLayout               string
+	timeLocation             *time.Location
+	encoder                  Encoder
+	namedConverters          map[string]ConverterFunc
+	report                   *MapReport
+	noExtraSourceFields      bool
+	deepCopy                 bool
+	requiredFields           []string
+	recoverHooks             bool
+	jsonKeys                 bool
+	positionalMatch          bool
+	sqlValueTypes            bool
+	tagParser                TagParser
+	resolver                 func(destField reflect.StructField, source reflect.Value) (reflect.Value, bool)
+	hookErrors               *[]error
+	// failedPath receives the path of the field that failed to map first,
+	// if it is set.
+	failedPath *string
+	// embedsSource is set while mapping the fields of a destination that
+	// embeds the source type, whose own fields may have no source field.
+	embedsSource bool
+
+	// sourcePath and destPath hold the dotted paths of the values being
+	// mapped, relative to the top level values.
+	sourcePath string
+	destPath   string
+	state      *mapState
+}
+
+func newMapOptions(useSourceMemberList bool, opts []Option) mapOptions {
+	var result = mapOptions{useSourceMemberList: useSourceMemberList}
+	for _, opt := range opts {
+		opt(&result)
+	}
+	if result.strict && result.maxDepth == 0 {
+		result.maxDepth = defaultStrictMaxDepth
+	}
+	return result
+}
+
+// WithOverflowCheck makes numeric conversions panic when the source value does
+// not fit in the destination type. By default such values are silently
+// truncated, like a regular Go conversion.
+func WithOverflowCheck() Option {
+	return func(o *mapOptions) {
+		o.overflowCheck = true
+	}
+}
+
+// WithPreserveSliceElements maps source slice elements onto the existing
+// elements of the destination slice, rather than replacing the destination
+// with a new slice. Fields not mapped from the source keep their values. The
+// destination is truncated or grown to the length of the source; grown
+// elements start out as zero values.
+func WithPreserveSliceElements() Option {
+	return func(o *mapOptions) {
+		o.preserveSliceElements = true
+	}
+}
+
+// WithStrict verifies that the two types are fully in sync. When mapping from
+// the source, every exported destination field must receive a value. When
+// mapping to the destination, every exported source field must be used. The
+// mapping panics listing the offending fields otherwise. Fields tagged with
+// `automapper:"-"` are exempt. The verification includes nested structs.
+// Unless WithMaxDepth is given as well, strict mappings are limited to a
+// depth of 32.
+func WithStrict() Option {
+	return func(o *mapOptions) {
+		o.strict = true
+	}
+}
+
+// WithNoExtraSourceFields verifies that every exported source field is used
+// when mapping to the destination, and panics listing the unused fields
+// otherwise. Source fields are used when a destination field maps from them,
+// under their own name or a renamed one. Fields tagged with `automapper:"-"`
+// on either side are exempt. Unlike WithStrict, destination fields without a
+// source field are not checked. The option has no effect when mapping from the
+// source, as every source field is used then.
+func WithNoExtraSourceFields() Option {
+	return func(o *mapOptions) {
+		o.noExtraSourceFields = true
+	}
+}
+
+// WithPositionalMatch maps struct fields by their position instead of their
+// name, so the first field of the source is mapped to the first field of the
+// destination, and so on. Names and tags are not looked at, which makes the
+// mapping faster. The two structs must have the same number of fields, and
+// the fields at each position must be of the same kind, otherwise the mapping
+// fails, and CompileMapper returns an error. Unexported fields are skipped.
+// This is meant for pairs of types that are kept in sync by hand.
+func WithPositionalMatch() Option {
+	return func(o *mapOptions) {
+		o.positionalMatch = true
+	}
+}
+
+// WithReuseNestedPointers maps into the values that non-nil destination
+// pointers already point to, instead of allocating new ones, e.g. to reuse
+// objects from a pool. Unlike MapInto, all other values are replaced as usual,
+// so a nil source pointer still sets the destination pointer to nil.
+func WithReuseNestedPointers() Option {
+	return func(o *mapOptions) {
+		o.reuseNestedPointers = true
+	}
+}
+
+// WithSQLValueTypes maps booleans, numbers, strings, byte slices and times
+// into types implementing sql.Scanner, such as sql.NullString, by passing them
+// to Scan. Nil pointers scan as nil. In the other direction, types
+// implementing driver.Valuer map into those values, and into scanners, through
+// the result of their Value method, where nil maps to the zero value. Errors
+// returned by Scan or Value abort the mapping.
+func WithSQLValueTypes() Option {
+	return func(o *mapOptions) {
+		o.sqlValueTypes = true
+	}
+}
+
+// WithTagParser interprets struct tags with parser instead of
+// DefaultTagParser, so types can use another tag syntax, e.g. options
+// separated by ";" or a tag key other than automapper. The options returned by
+// parser are interpreted like the options of the automapper tag, where a
+// "default=" option takes all of the options following it as its value.
+func WithTagParser(parser TagParser) Option {
+	return func(o *mapOptions) {
+		o.tagParser = parser
+	}
+}
+
+// WithTagMatching treats automapper tags on both types as shared logical
+// names. A field is matched with the field on the other type that has the
+// same tag, or the same name when that field has no tag. This allows two
+// types to be mapped without either one referring to the field names of the
+// other.
+func WithTagMatching() Option {
+	return func(o *mapOptions) {
+		o.tagMatching = true
+	}
+}
+
+// WithEnum maps between an integer enum type and a string enum type, in both
+// directions, by looking up the values in values. Mapping a value that is not
+// in values fails with an *UnknownEnumValueError.
+func WithEnum(intType, stringType reflect.Type, values map[int]string) Option {
+	return enumOption("WithEnum", intType, stringType, values, nil)
+}
+
+// WithEnumFallback works like WithEnum, but maps values that are not in values
+// to fallback, or to the name of fallback when mapping to stringType, rather
+// than failing. fallback must be one of the values.
+func WithEnumFallback(intType, stringType reflect.Type, values map[int]string, fallback int) Option {
+	if _, ok := values[fallback]; !ok {
+		panic(fmt.Sprintf("WithEnumFallback requires the fallback %d to be one of the values", fallback))
+	}
+	return enumOption("WithEnumFallback", intType, stringType, values, &fallback)
+}
+
+func enumOption(name string, intType, stringType reflect.Type, values map[int]string, fallback *int) Option {
+	if !isIntKind(intType.Kind()) || stringType.Kind() != reflect.String {
+		panic(fmt.Sprintf("%s requires an integer and a string type, got %v and %v", name, intType, stringType))
+	}
+	names := make(map[int64]string, len(values))
+	numbers := make(map[string]int64, len(values))
+	for i, s := range values {
+		names[int64(i)] = s
+		numbers[s] = int64(i)
+	}
+	return func(o *mapOptions) {
+		o.addConverter(intType, stringType, func(_ context.Context, sourceVal reflect.Value) reflect.Value {
+			s, ok := names[sourceVal.Int()]
+			if !ok {
+				if fallback == nil {
+					panic(&UnknownEnumValueError{Type: intType, Value: sourceVal.Interface()})
+				}
+				s = values[*fallback]
+			}
+			return reflect.ValueOf(s).Convert(stringType)
+		})
+		o.addConverter(stringType, intType, func(_ context.Context, sourceVal reflect.Value) reflect.Value {
+			i, ok := numbers[sourceVal.String()]
+			if !ok {
+				if fallback == nil {
+					panic(&UnknownEnumValueError{Type: stringType, Value: sourceVal.Interface()})
+				}
+				i = int64(*fallback)
+			}
+			return reflect.ValueOf(i).Convert(intType)
+		})
+	}
+}
+
+// UnknownEnumValueError is the error for a value that is not one of the
+// values of an enum registered with WithEnum.
+type UnknownEnumValueError struct {
+	// Type is the enum type of Value.
+	Type  reflect.Type
+	Value interface{}
+}
+
+func (e *UnknownEnumValueError) Error() string {
+	return fmt.Sprintf("unknown value %#v for enum %v", e.Value, e.Type)
+}
+
+// WithKeyNamer sets the function used by MapToMap to turn field names into map
+// keys, e.g. to produce snake_case keys. It is applied to every field name,
+// after automapper tags are applied, at every level of nesting.
+func WithKeyNamer(namer func(fieldName string) string) Option {
+	return func(o *mapOptions) {
+		o.keyNamer = namer
+	}
+}
+
+// WithKeyNormalizer sets the function applied to the keys of maps that are
+// mapped into structs, e.g. by MapFromSourceMap, before they are matched with
+// the field names, e.g. to fix their casing or trim them. With
+// MapFromSourceMapJSON, the normalized keys are matched with the json tags.
+func WithKeyNormalizer(normalize func(key string) string) Option {
+	return func(o *mapOptions) {
+		o.keyNormalizer = normalize
+	}
+}
+
+// WithJSONDecode decodes JSON when mapping a byte slice, e.g. a
+// json.RawMessage, to a struct or a pointer to a struct, using
+// json.Unmarshal. An empty source leaves the destination unchanged.
+func WithJSONDecode() Option {
+	return func(o *mapOptions) {
+		o.jsonDecode = true
+	}
+}
+
+// WithFieldTransform registers a function that transforms the value of the
+// destination field at path. The path is the dotted path of the field from
+// the top level destination, e.g. "Email" or "Contacts.Email", where slice
+// elements share the path of the slice. The transform receives the mapped
+// value, already converted to the type of the field, and returns the value to
+// store, which must be assignable to the field.
+func WithFieldTransform(path string, transform func(value interface{}) interface{}) Option {
+	return func(o *mapOptions) {
+		if o.fieldTransforms == nil {
+			o.fieldTransforms = map[string]func(interface{}) interface{}{}
+		}
+		o.fieldTransforms[path] = transform
+	}
+}
+
+// WithNilSlicesAsEmpty maps nil source slices to empty, non-nil destination
+// slices. By default a nil source slice results in a nil destination slice,
+// which e.g. encoding/json renders as null rather than [].
+func WithNilSlicesAsEmpty() Option {
+	return func(o *mapOptions) {
+		o.nilSlicesAsEmpty = true
+	}
+}
+
+// WithDefaultOnConversionError tolerates values that cannot be converted to
+// the type of their destination, e.g. because a converter returns an error or
+// a number overflows. Such destinations are left at their zero value rather
+// than aborting the mapping. Each failure is appended to warnings as a
+// *ConversionError, unless warnings is nil. Structural mismatches, like a
+// missing source field or a struct mapped to a number, still abort the
+// mapping.
+func WithDefaultOnConversionError(warnings *[]error) Option {
+	return func(o *mapOptions) {
+		o.defaultOnConversionError = true
+		o.conversionWarnings = warnings
+	}
+}
+
+// WithResolver lets resolver supply the source value of every destination
+// field when mapping to the destination. It is called with the destination
+// field and the source struct before the source field is looked up as usual.
+// When it returns true, the value it returns is mapped into the field like a
+// source field would be, and an invalid value maps to the zero value. When it
+// returns false, the field is mapped as usual. A panic in resolver is reported
+// like any other error mapping the field. Fields tagged `automapper:"-"` and
+// embedded structs are not passed to resolver.
+func WithResolver(resolver func(destField reflect.StructField, source reflect.Value) (reflect.Value, bool)) Option {
+	return func(o *mapOptions) {
+		o.resolver = resolver
+	}
+}
+
+// WithRecoverHooks recovers panics raised by the functions passed as options
+// that map values, i.e. converters, field transforms and resolvers. The
+// destination of such a value is left at its zero value, and the mapping
+// continues with the next one. Panics raised by methods of the mapped types,
+// like getters, setters and Validate, are not recovered. Each panic is
+// appended to errs as a *HookPanicError, unless errs is nil. Errors returned
+// by converters still abort the mapping. Without this option, a panicking hook
+// aborts the mapping, so bugs are easily noticed.
+func WithRecoverHooks(errs *[]error) Option {
+	return func(o *mapOptions) {
+		o.recoverHooks = true
+		o.hookErrors = errs
+	}
+}
+
+// WithSliceFilter only maps the elements of source slices for which filter
+// returns true, so the destination slice holds just the passing elements. The
+// filter is called with each source element. Values of identical struct types
+// are still copied as a whole, including their slices.
+func WithSliceFilter(filter func(sourceElem interface{}) bool) Option {
+	return func(o *mapOptions) {
+		o.sliceFilter = filter
+	}
+}
+
+// WithValidation calls Validate on every destination struct that implements
+// Validatable, once it has been mapped. This includes structs of the same type
+// as their source, which are copied as a whole, and structs produced by a
+// converter. Nested structs are validated before the structs containing them.
+// The first validation error aborts the mapping, and is returned by the
+// functions that return errors.
+func WithValidation() Option {
+	return func(o *mapOptions) {
+		o.validate = true
+	}
+}
+
+// WithRequiredFields verifies that the destination fields at paths are not
+// empty once the mapping is done, including the default values applied by
+// tags. A path is the dotted path of a destination field, e.g.
+// "Tenant.ID". The mapping panics listing the empty fields otherwise, and the
+// functions that return errors return this as an error.
+func WithRequiredFields(paths ...string) Option {
+	return func(o *mapOptions) {
+		o.requiredFields = append(o.requiredFields, paths...)
+	}
+}
+
+// WithSingleElementSlices maps a single struct into a destination slice as a
+// slice holding just that element, and a slice into a destination struct by
+// mapping its first element. An empty slice maps to the zero value of the
+// struct. Without this option, such mappings fail as type mismatches.
+func WithSingleElementSlices() Option {
+	return func(o *mapOptions) {
+		o.wrapSingleElements = true
+	}
+}
+
+// WithScalarWrapping maps a boolean, number or string into a destination
+// struct, or a newly allocated pointer to one, by mapping it into the only
+// exported field of the struct it can be mapped to, e.g. a string into a
+// value object like struct{ Value string }. The other fields are left
+// unchanged. It is an error if no field, or more than one field, fits.
+func WithScalarWrapping() Option {
+	return func(o *mapOptions) {
+		o.wrapScalars = true
+	}
+}
+
+// WithMaxDepth makes the mapping fail once values are nested more than n
+// levels deep, which guards against exhausting the stack when mapping
+// untrusted data. Every pointer, struct field, slice element and map entry
+// followed counts as a level. A depth of 0 means no limit.
+func WithMaxDepth(n int) Option {
+	return func(o *mapOptions) {
+		o.maxDepth = n
+	}
+}
+
+// WithGetters reads source values through getter methods when the source has
+// no field of the name that is looked up. A method named Get<Field> is
+// preferred over a method named <Field>. Only methods without arguments that
+// return a single value are used.
+func WithGetters() Option {
+	return func(o *mapOptions) {
+		o.getters = true
+	}
+}
+
+// WithSetters writes destination values through setter methods named
+// Set<Field> when the destination has no exported field of that name. The
+// source value is mapped to the type of the single argument of the setter.
+func WithSetters() Option {
+	return func(o *mapOptions) {
+		o.setters = true
+	}
+}
+
+// WithDeepCopy copies values of identical types recursively, instead of
+// assigning them. The destination then shares no pointers, slices or maps
+// with the source. Values stored in interfaces are copied according to their
+// dynamic type, so e.g. a []interface{} holding different structs is copied
+// element by element. Errors are kept as they are, so errors.Is still finds
+// the errors of the source. Pointers of identical types are always copied this
+// way, even without this option.
+func WithDeepCopy() Option {
+	return func(o *mapOptions) {
+		o.deepCopy = true
+	}
+}
+
+// WithSharedPointers maps every source pointer only once. When the same
+// pointer occurs multiple times in the source, all occurrences in the
+// destination point to the same mapped value, so shared nodes of a graph stay
+// shared. This also makes it possible to map graphs with cycles.
+func WithSharedPointers() Option {
+	return func(o *mapOptions) {
+		o.sharedPointers = true
+	}
+}
+
+// WithArrayResize allows mapping slices and arrays to arrays of a different
+// length. Excess source elements are dropped, and missing ones leave the
+// remaining array elements at their zero value. Without this option, the
+// lengths must be equal.
+func WithArrayResize() Option {
+	return func(o *mapOptions) {
+		o.resizeArrays = true
+	}
+}
+
+// WithLogger calls logger with the destination path and a description of the
+// decisions taken while mapping, e.g. skipped fields, source fields found
+// through embedded or nested structs, conversions and nil sources. It is meant
+// for finding out why a field was not mapped as expected.
+func WithLogger(logger func(path, msg string)) Option {
+	return func(o *mapOptions) {
+		o.logger = logger
+	}
+}
+
+// WithIsZero defines which values of type t count as empty, for types whose
+// neutral value is not the zero value of the type. It replaces the zero value
+// check wherever values are tested for being empty, e.g. for fields tagged
+// omitempty or with a default value.
+func WithIsZero(t reflect.Type, isZero func(value interface{}) bool) Option {
+	return func(o *mapOptions) {
+		if o.isZeroFuncs == nil {
+			o.isZeroFuncs = map[reflect.Type]func(interface{}) bool{}
+		}
+		o.isZeroFuncs[t] = isZero
+	}
+}
+
+// WithIgnorePattern skips the fields whose names match pattern, like fields
+// tagged "-". The pattern applies to the fields of the type driving the
+// mapping, i.e. the destination fields for MapToDestination and the source
+// fields for MapFromSource.
+func WithIgnorePattern(pattern *regexp.Regexp) Option {
+	return func(o *mapOptions) {
+		o.ignorePattern = pattern
+	}
+}
+
+// WithStrictTypes forbids conversions that may lose information or change the
+// meaning of a value, like float64 to int, which truncates, or int to string,
+// which yields the string of a single rune. Only values of assignable types,
+// or of types of the same kind, e.g. a named type and its underlying type,
+// are converted. Other conversions panic, unless a converter is registered
+// for them.
+func WithStrictTypes() Option {
+	return func(o *mapOptions) {
+		o.strictTypes = true
+	}
+}
+
+// WithStripPrefix matches fields whose names only differ by one of the given
+// prefixes, e.g. with the prefix "User", a field UserID matches a field ID of
+// the other type, and the other way around. Exact matches are always
+// preferred.
+func WithStripPrefix(prefixes ...string) Option {
+	return func(o *mapOptions) {
+		o.prefixes = append(o.prefixes, prefixes...)
+	}
+}
+
+// WithStripSuffix matches fields whose names only differ by one of the given
+// suffixes, like WithStripPrefix does for prefixes.
+func WithStripSuffix(suffixes ...string) Option {
+	return func(o *mapOptions) {
+		o.suffixes = append(o.suffixes, suffixes...)
+	}
+}
+
+// WithUnsafeUnexported maps unexported fields like exported ones, reading and
+// writing them through package unsafe. This is meant for copying trusted
+// values, e.g. to clone a type with private state in tests.
+//
+// Use it with care: it bypasses the encapsulation of the types involved, so
+// it can break their invariants, and it relies on implementation details of
+// those types that may change without notice. Unexported fields of values
+// that are not addressable, like map values, still cannot be mapped.
+func WithUnsafeUnexported() Option {
+	return func(o *mapOptions) {
+		o.unsafeUnexported = true
+	}
+}
+
+// WithKeyLess orders the keys of a map by less when the values of the map are
+// mapped into a slice. By default, numeric keys are ordered numerically and
+// string keys lexically, and keys of other types cannot be ordered.
+func WithKeyLess(less func(a, b interface{}) bool) Option {
+	return func(o *mapOptions) {
+		o.keyLess = less
+	}
+}
+
+// WithTimeLayout sets the layout used to convert between strings and
+// time.Time values, as understood by time.Parse. The default is
+// time.RFC3339Nano. Empty strings and zero times convert to each other.
+func WithTimeLayout(layout string) Option {
+	return func(o *mapOptions) {
+		o.timeLayout = layout
+	}
+}
+
+// WithTimeLocation sets the location that times are converted to when they
+// are converted from strings or Unix timestamps, and that times are formatted
+// in when they are converted to strings. Strings without a time zone are
+// parsed in the location as well. The default location is UTC.
+func WithTimeLocation(location *time.Location) Option {
+	return func(o *mapOptions) {
+		o.timeLocation = location
+	}
+}
+
+// WithEncoder encodes structs, and pointers to structs, with encoder when they
+// are mapped to a byte slice, and decodes byte slices with it when they are
+// mapped to a struct. This allows storing a whole struct as a blob. Encoding
+// and decoding errors abort the mapping.
+func WithEncoder(encoder Encoder) Option {
+	return func(o *mapOptions) {
+		o.encoder = encoder
+	}
+}
@@ -0,0 +1,606 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverflowIsTruncatedByDefault(t *testing.T) {
+	source := struct{ Foo int64 }{math.MaxInt32 + 1}
+	dest := struct{ Foo int32 }{}
+	MapToDestination(&source, &dest)
+	assert.Equal(t, int32(math.MinInt32), dest.Foo)
+}
+
+func TestWithOverflowCheck(t *testing.T) {
+	defer func() {
+		r := recover()
+		assert.Contains(t, r, "overflows int32")
+	}()
+	source := struct{ Foo int64 }{math.MaxInt32 + 1}
+	dest := struct{ Foo int32 }{}
+	MapToDestination(&source, &dest, WithOverflowCheck())
+	t.Error("Should have panicked")
+}
+
+func TestWithOverflowCheckValueFits(t *testing.T) {
+	source := struct {
+		Foo int64
+		Bar float64
+		Baz int
+	}{math.MaxInt32, 1.5, 255}
+	dest := struct {
+		Foo int32
+		Bar float32
+		Baz uint8
+	}{}
+	MapToDestination(&source, &dest, WithOverflowCheck())
+	assert.Equal(t, int32(math.MaxInt32), dest.Foo)
+	assert.Equal(t, float32(1.5), dest.Bar)
+	assert.Equal(t, uint8(255), dest.Baz)
+}
+
+func TestWithOverflowCheckNegativeToUnsigned(t *testing.T) {
+	defer func() { recover() }()
+	source := struct{ Foo int }{-1}
+	dest := struct{ Foo uint }{}
+	MapToDestination(&source, &dest, WithOverflowCheck())
+	t.Error("Should have panicked")
+}
+
+func TestWithOverflowCheckFloat(t *testing.T) {
+	defer func() { recover() }()
+	source := struct{ Foo float64 }{math.MaxFloat64}
+	dest := struct{ Foo float32 }{}
+	MapToDestination(&source, &dest, WithOverflowCheck())
+	t.Error("Should have panicked")
+}
+
+func TestWithPreserveSliceElements(t *testing.T) {
+	type destElem struct {
+		Foo int
+		Baz string
+	}
+	source := struct {
+		Children []struct{ Foo int }
+	}{[]struct{ Foo int }{{1}, {2}, {3}}}
+	dest := struct {
+		Children []destElem
+	}{}
+
+	dest.Children = []destElem{{Foo: 10, Baz: "a"}, {Foo: 20, Baz: "b"}}
+	MapFromSource(&source, &dest, WithPreserveSliceElements())
+	assert.Equal(t, []destElem{{1, "a"}, {2, "b"}, {3, ""}}, dest.Children)
+}
+
+func TestWithPreserveSliceElementsTruncates(t *testing.T) {
+	type destElem struct {
+		Foo int
+		Baz string
+	}
+	source := struct {
+		Children []struct{ Foo int }
+	}{[]struct{ Foo int }{{1}}}
+	dest := struct {
+		Children []destElem
+	}{[]destElem{{Foo: 10, Baz: "a"}, {Foo: 20, Baz: "b"}}}
+
+	MapFromSource(&source, &dest, WithPreserveSliceElements())
+	assert.Equal(t, []destElem{{1, "a"}}, dest.Children)
+}
+
+func TestSliceElementsAreReplacedByDefault(t *testing.T) {
+	type destElem struct {
+		Foo int
+		Baz string
+	}
+	source := struct {
+		Children []struct{ Foo int }
+	}{[]struct{ Foo int }{{1}}}
+	dest := struct {
+		Children []destElem
+	}{[]destElem{{Foo: 10, Baz: "a"}}}
+
+	MapFromSource(&source, &dest)
+	assert.Equal(t, []destElem{{1, ""}}, dest.Children)
+}
+
+func TestWithOverflowCheckInSlices(t *testing.T) {
+	defer func() { recover() }()
+	source := struct{ Foo []int }{[]int{1, 300}}
+	dest := struct{ Foo []uint8 }{}
+	MapToDestination(&source, &dest, WithOverflowCheck())
+	t.Error("Should have panicked")
+}
+
+type Status int
+
+type StatusDTO string
+
+var statusEnum = WithEnum(reflect.TypeOf(Status(0)), reflect.TypeOf(StatusDTO("")), map[int]string{
+	0: "unknown",
+	1: "active",
+})
+
+func TestWithEnum(t *testing.T) {
+	source := struct{ Status Status }{1}
+	dest := struct{ Status StatusDTO }{}
+	MapToDestination(&source, &dest, statusEnum)
+	assert.Equal(t, StatusDTO("active"), dest.Status)
+
+	MapToDestination(&dest, &source, statusEnum)
+	assert.Equal(t, Status(1), source.Status)
+}
+
+func TestWithEnumUnknownValue(t *testing.T) {
+	source := struct{ Status StatusDTO }{"deleted"}
+	dest := struct{ Status Status }{}
+	err := NewMapper(statusEnum).MapDir(&source, &dest, ToDestination)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown value "deleted"`)
+}
+
+func TestWithEnumUnknownValueError(t *testing.T) {
+	source := struct{ Status StatusDTO }{"UNKNOWN_STATUS"}
+	dest := struct{ Status Status }{}
+	err := NewMapper(statusEnum).MapDir(&source, &dest, ToDestination)
+
+	var enumErr *UnknownEnumValueError
+	if assert.True(t, errors.As(err, &enumErr)) {
+		assert.Equal(t, reflect.TypeOf(StatusDTO("")), enumErr.Type)
+		assert.Equal(t, StatusDTO("UNKNOWN_STATUS"), enumErr.Value)
+	}
+}
+
+func TestWithEnumFallback(t *testing.T) {
+	lenient := WithEnumFallback(reflect.TypeOf(Status(0)), reflect.TypeOf(StatusDTO("")), map[int]string{
+		0: "unknown",
+		1: "active",
+	}, 0)
+	source := struct{ Status StatusDTO }{"UNKNOWN_STATUS"}
+	dest := struct{ Status Status }{1}
+	MapToDestination(&source, &dest, lenient)
+	assert.Equal(t, Status(0), dest.Status)
+
+	dest.Status = 7
+	MapToDestination(&dest, &source, lenient)
+	assert.Equal(t, StatusDTO("unknown"), source.Status)
+}
+
+func TestWithEnumFallbackRequiresAKnownFallback(t *testing.T) {
+	defer func() { recover() }()
+	WithEnumFallback(reflect.TypeOf(Status(0)), reflect.TypeOf(StatusDTO("")), map[int]string{1: "active"}, 0)
+	t.Error("Should have panicked")
+}
+
+func TestWithFieldTransform(t *testing.T) {
+	type Contact struct {
+		Email string
+	}
+	source := struct {
+		Email    string
+		Name     string
+		Contacts []Contact
+	}{"A@B.COM", "Name", []Contact{{"C@D.COM"}}}
+	dest := struct {
+		Email    string
+		Name     string `automapper:"Name"`
+		Contacts []struct{ Email string }
+	}{}
+	lower := func(v interface{}) interface{} { return strings.ToLower(v.(string)) }
+
+	MapToDestination(&source, &dest,
+		WithFieldTransform("Email", lower),
+		WithFieldTransform("Contacts.Email", lower))
+	assert.Equal(t, "a@b.com", dest.Email)
+	assert.Equal(t, "Name", dest.Name)
+	assert.Equal(t, "c@d.com", dest.Contacts[0].Email)
+}
+
+func TestWithFieldTransformForIdenticalTypes(t *testing.T) {
+	type Contact struct {
+		Email string
+	}
+	type Person struct {
+		Email    string
+		Contacts []Contact
+	}
+	source := Person{"A@B.COM", []Contact{{"C@D.COM"}}}
+	lower := func(v interface{}) interface{} { return strings.ToLower(v.(string)) }
+	opts := []Option{WithFieldTransform("Email", lower), WithFieldTransform("Contacts.Email", lower)}
+	expected := Person{"a@b.com", []Contact{{"c@d.com"}}}
+
+	dest := Person{}
+	MapToDestination(&source, &dest, opts...)
+	assert.Equal(t, expected, dest)
+
+	dest = Person{}
+	MustCompileMapper(reflect.TypeOf(source), reflect.TypeOf(dest), opts...)(&source, &dest)
+	assert.Equal(t, expected, dest)
+	assert.Equal(t, "A@B.COM", source.Email)
+}
+
+func TestWithFieldTransformSeesConvertedValue(t *testing.T) {
+	source := struct{ Foo int }{21}
+	dest := struct{ Foo int64 }{}
+
+	MapToDestination(&source, &dest, WithFieldTransform("Foo", func(v interface{}) interface{} {
+		return v.(int64) * 2
+	}))
+	assert.Equal(t, int64(42), dest.Foo)
+}
+
+func TestNilSliceMapsToNilByDefault(t *testing.T) {
+	source := struct {
+		Children []SourceTypeA
+		Values   []int
+	}{}
+	dest := struct {
+		Children []DestTypeA
+		Values   []int
+	}{[]DestTypeA{{}}, []int{1}}
+
+	MapToDestination(&source, &dest)
+	assert.Nil(t, dest.Children)
+	assert.Nil(t, dest.Values)
+}
+
+func TestWithNilSlicesAsEmpty(t *testing.T) {
+	source := struct {
+		Children []SourceTypeA
+		Values   []int
+	}{}
+	dest := struct {
+		Children []DestTypeA
+		Values   []int
+	}{}
+
+	MapToDestination(&source, &dest, WithNilSlicesAsEmpty())
+	assert.NotNil(t, dest.Children)
+	assert.Len(t, dest.Children, 0)
+	assert.NotNil(t, dest.Values)
+	assert.Len(t, dest.Values, 0)
+}
+
+func TestNilSliceOfIncompatibleTypes(t *testing.T) {
+	defer func() { recover() }()
+	source := struct {
+		Children []struct{ Foo string }
+	}{}
+	dest := struct {
+		Children []struct{ Bar int }
+	}{}
+
+	MapToDestination(&source, &dest)
+	t.Error("Should have panicked")
+}
+
+func TestWithSliceFilter(t *testing.T) {
+	type record struct {
+		Name    string
+		Deleted bool
+	}
+	source := struct{ Records []record }{[]record{{"a", false}, {"b", true}, {"c", false}}}
+	dest := struct{ Records []struct{ Name string } }{}
+	notDeleted := WithSliceFilter(func(elem interface{}) bool {
+		return !elem.(record).Deleted
+	})
+
+	MapToDestination(&source, &dest, notDeleted)
+	if assert.Len(t, dest.Records, 2) {
+		assert.Equal(t, "a", dest.Records[0].Name)
+		assert.Equal(t, "c", dest.Records[1].Name)
+	}
+}
+
+func TestWithSliceFilterOnIdenticalSliceTypes(t *testing.T) {
+	source := []int{1, 2, 3, 4}
+	var dest []int
+	even := WithSliceFilter(func(elem interface{}) bool {
+		return elem.(int)%2 == 0
+	})
+
+	MapToDestination(source, &dest, even)
+	assert.Equal(t, []int{2, 4}, dest)
+	assert.Equal(t, []int{1, 2, 3, 4}, source)
+}
+
+func TestWithSingleElementSlicesWrapsStruct(t *testing.T) {
+	source := struct{ Item SourceTypeA }{SourceTypeA{Foo: 1, Bar: "a"}}
+	dest := struct{ Item []DestTypeA }{}
+
+	MapToDestination(&source, &dest, WithSingleElementSlices())
+	assert.Equal(t, []DestTypeA{{Foo: 1, Bar: "a"}}, dest.Item)
+}
+
+func TestWithSingleElementSlicesUnwrapsFirstElement(t *testing.T) {
+	source := struct{ Item []SourceTypeA }{[]SourceTypeA{{Foo: 1}, {Foo: 2}}}
+	dest := struct{ Item *DestTypeA }{}
+
+	MapToDestination(&source, &dest, WithSingleElementSlices())
+	assert.Equal(t, 1, dest.Item.Foo)
+
+	source.Item = nil
+	MapToDestination(&source, &dest, WithSingleElementSlices())
+	assert.Equal(t, &DestTypeA{}, dest.Item)
+}
+
+func TestSingleElementSlicesAreOptIn(t *testing.T) {
+	defer func() { recover() }()
+	source := struct{ Item SourceTypeA }{}
+	dest := struct{ Item []DestTypeA }{}
+
+	MapToDestination(&source, &dest)
+	t.Error("Should have panicked")
+}
+
+func TestWithScalarWrapping(t *testing.T) {
+	type Name struct{ Value string }
+	type Money struct {
+		Amount   float64
+		Currency string
+	}
+	title := "title"
+	source := struct {
+		Name    string
+		Title   *string
+		Missing *string
+		Price   int
+	}{"name", &title, nil, 42}
+	dest := struct {
+		Name    *Name
+		Title   Name
+		Missing *Name
+		Price   Money
+	}{Missing: &Name{}, Price: Money{Currency: "EUR"}}
+
+	MapToDestination(&source, &dest, WithScalarWrapping())
+	assert.Equal(t, &Name{"name"}, dest.Name)
+	assert.Equal(t, Name{"title"}, dest.Title)
+	assert.Nil(t, dest.Missing)
+	assert.Equal(t, Money{Amount: 42, Currency: "EUR"}, dest.Price)
+}
+
+func TestWithScalarWrappingPanicsWhenAmbiguous(t *testing.T) {
+	source := struct{ Name string }{"name"}
+	dest := struct{ Name *struct{ First, Last string } }{}
+
+	err := NewMapper(WithScalarWrapping()).MapDir(&source, &dest, ToDestination)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot wrap string in struct { First string; Last string }: fields [First, Last] can all hold it")
+
+	other := struct{ Name struct{ Count int } }{}
+	err = NewMapper(WithScalarWrapping()).MapDir(&source, &other, ToDestination)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no field can hold it")
+}
+
+func TestScalarWrappingIsOptIn(t *testing.T) {
+	defer func() { recover() }()
+	source := struct{ Name string }{"name"}
+	dest := struct{ Name *struct{ Value string } }{}
+
+	MapToDestination(&source, &dest)
+	t.Error("Should have panicked")
+}
+
+type nestedNode struct {
+	Value int
+	Next  *nestedNode
+}
+
+type nestedNodeDTO struct {
+	Value int
+	Next  *nestedNodeDTO
+}
+
+func newNestedNodes(n int) *nestedNode {
+	var head *nestedNode
+	for i := 0; i < n; i++ {
+		head = &nestedNode{Value: i, Next: head}
+	}
+	return head
+}
+
+func TestWithMaxDepth(t *testing.T) {
+	dest := nestedNodeDTO{}
+	err := NewMapper(WithMaxDepth(10)).MapDir(newNestedNodes(3), &dest, ToDestination)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, dest.Next.Next.Value)
+
+	err = NewMapper(WithMaxDepth(10)).MapDir(newNestedNodes(100), &dest, ToDestination)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "maximum mapping depth of 10 exceeded")
+}
+
+func TestStrictHasDefaultMaxDepth(t *testing.T) {
+	dest := nestedNodeDTO{}
+	err := NewMapper(WithStrict()).MapDir(newNestedNodes(100), &dest, ToDestination)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "maximum mapping depth of 32 exceeded")
+
+	err = NewMapper(WithStrict(), WithMaxDepth(1000)).MapDir(newNestedNodes(100), &dest, ToDestination)
+	assert.NoError(t, err)
+}
+
+func TestWithArrayResize(t *testing.T) {
+	source := struct{ Items []int }{[]int{1, 2, 3}}
+	dest := struct{ Items [2]int }{}
+
+	MapToDestination(&source, &dest, WithArrayResize())
+	assert.Equal(t, [2]int{1, 2}, dest.Items)
+
+	short := struct{ Items []int }{[]int{7}}
+	MapToDestination(&short, &dest, WithArrayResize())
+	assert.Equal(t, [2]int{7, 0}, dest.Items)
+}
+
+func TestWithLogger(t *testing.T) {
+	source := struct {
+		Child *SourceTypeA
+		SourceTypeA
+	}{}
+	dest := struct {
+		Child *DestTypeA
+		Foo   int `automapper:",omitempty"`
+		Bar   int `automapper:"-"`
+	}{}
+	var messages []string
+	logger := WithLogger(func(path, msg string) {
+		messages = append(messages, path+": "+msg)
+	})
+
+	MapToDestination(&source, &dest, logger)
+	assert.Equal(t, []string{
+		"Child: source is nil, leaving destination pointer nil",
+		"Foo: resolved source field 'Foo' to 'SourceTypeA.Foo'",
+		"Foo: source value is empty, skipping field tagged omitempty",
+		"Bar: skipping field tagged \"-\"",
+	}, messages)
+}
+
+type money struct {
+	Currency string
+	Amount   int
+}
+
+func TestWithIsZero(t *testing.T) {
+	source := struct {
+		Price money
+		Tax   money
+	}{money{"USD", 0}, money{"USD", 5}}
+	dest := struct {
+		Price money `automapper:",omitempty"`
+		Tax   money `automapper:",omitempty"`
+	}{money{"EUR", 10}, money{}}
+	moneyIsZero := WithIsZero(reflect.TypeOf(money{}), func(value interface{}) bool {
+		return value.(money).Amount == 0
+	})
+
+	MapToDestination(&source, &dest, moneyIsZero)
+	assert.Equal(t, money{"EUR", 10}, dest.Price)
+	assert.Equal(t, money{"USD", 5}, dest.Tax)
+}
+
+func TestWithIgnorePattern(t *testing.T) {
+	source := struct {
+		Foo            int
+		Bar_deprecated int
+	}{1, 2}
+	dest := struct {
+		Foo            int
+		Bar_deprecated int
+		Baz_deprecated int
+	}{0, 0, 3}
+
+	MapToDestination(&source, &dest, WithIgnorePattern(regexp.MustCompile(`_deprecated$`)))
+	assert.Equal(t, 1, dest.Foo)
+	assert.Equal(t, 0, dest.Bar_deprecated)
+	assert.Equal(t, 3, dest.Baz_deprecated)
+}
+
+func TestWithIgnorePatternFromSource(t *testing.T) {
+	source := struct {
+		Foo       int
+		XInternal string
+	}{1, "x"}
+	dest := struct{ Foo int }{}
+
+	MapFromSource(&source, &dest, WithIgnorePattern(regexp.MustCompile(`^X`)))
+	assert.Equal(t, 1, dest.Foo)
+}
+
+func TestWithStrictTypes(t *testing.T) {
+	for _, test := range []struct {
+		name         string
+		source, dest interface{}
+	}{
+		{"float to int", &struct{ Foo float64 }{1.5}, &struct{ Foo int }{}},
+		{"int to string", &struct{ Foo int }{65}, &struct{ Foo string }{}},
+		{"int64 to int32", &struct{ Foo int64 }{1}, &struct{ Foo int32 }{}},
+		{"slice of int to slice of string", &struct{ Foo []int }{[]int{65}}, &struct{ Foo []string }{}},
+	} {
+		err := SafeMap(func() { MapToDestination(test.source, test.dest, WithStrictTypes()) })
+		if assert.Error(t, err, test.name) {
+			assert.Contains(t, err.Error(), "with strict types", test.name)
+		}
+	}
+}
+
+func TestWithStrictTypesAllowsLosslessConversions(t *testing.T) {
+	source := struct {
+		Status Status
+		Name   string
+		Data   string
+	}{2, "foo", "bar"}
+	dest := struct {
+		Status int
+		Name   string
+		Data   []byte
+	}{}
+
+	MapToDestinationStrictTypes(&source, &dest)
+	assert.Equal(t, 2, dest.Status)
+	assert.Equal(t, "foo", dest.Name)
+	assert.Equal(t, []byte("bar"), dest.Data)
+}
+
+func TestWithStrictTypesAllowsRegisteredConverters(t *testing.T) {
+	source := struct{ Foo int }{65}
+	dest := struct{ Foo string }{}
+	itoa := WithConverter(reflect.TypeOf(0), reflect.TypeOf(""), func(_ context.Context, source interface{}) (interface{}, error) {
+		return strconv.Itoa(source.(int)), nil
+	})
+
+	MapToDestinationStrictTypes(&source, &dest, itoa)
+	assert.Equal(t, "65", dest.Foo)
+}
+
+func TestWithResolver(t *testing.T) {
+	source := struct {
+		First, Last string
+		Age         int
+	}{"John", "Doe", 42}
+	dest := struct {
+		FullName string
+		Age      int64
+		Nickname string
+	}{Nickname: "old"}
+	resolver := WithResolver(func(destField reflect.StructField, source reflect.Value) (reflect.Value, bool) {
+		switch destField.Name {
+		case "FullName":
+			return reflect.ValueOf(source.FieldByName("First").String() + " " + source.FieldByName("Last").String()), true
+		case "Nickname":
+			return reflect.Value{}, true
+		}
+		return reflect.Value{}, false
+	})
+
+	MapToDestination(&source, &dest, resolver)
+	assert.Equal(t, "John Doe", dest.FullName)
+	assert.Equal(t, int64(42), dest.Age)
+	assert.Equal(t, "", dest.Nickname)
+}
+
+func TestWithResolverErrorsAreAttributedToTheField(t *testing.T) {
+	source := struct{ Foo string }{"foo"}
+	dest := struct{ Foo int }{}
+	resolver := WithResolver(func(destField reflect.StructField, source reflect.Value) (reflect.Value, bool) {
+		panic("resolver failed")
+	})
+
+	err := NewMapper(resolver).MapDir(&source, &dest, ToDestination)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Error mapping field: Foo")
+	assert.Contains(t, err.Error(), "resolver failed")
+}
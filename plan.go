@@ -0,0 +1,125 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"reflect"
+	"strings"
+)
+
+// FieldMapping describes where MapToDestination takes the value of a single
+// destination field from. Paths are dotted field names relative to the top
+// level types, e.g. "Address.Street".
+type FieldMapping struct {
+	// SourcePath is the path of the source field providing the value. It is
+	// empty if no matching source field exists.
+	SourcePath string
+	// DestPath is the path of the destination field.
+	DestPath string
+	// Skipped is true when the destination field is excluded from the
+	// mapping by an `automapper:"-"` tag.
+	Skipped bool
+}
+
+// ResolvePlan returns the field mappings MapToDestination would perform when
+// mapping a value of sourceType to a value of destType, without copying any
+// data. Nested structs of different types are resolved field by field. Both
+// types must be structs, or pointers to structs, otherwise nil is returned.
+func ResolvePlan(sourceType, destType reflect.Type) []FieldMapping {
+	sourceType, destType = derefType(sourceType), derefType(destType)
+	if sourceType.Kind() != reflect.Struct || destType.Kind() != reflect.Struct {
+		return nil
+	}
+	return resolveFields(sourceType, destType, "", "")
+}
+
+func resolveFields(sourceType, destType reflect.Type, sourcePrefix, destPrefix string) []FieldMapping {
+	var plan []FieldMapping
+	for i := 0; i < destType.NumField(); i++ {
+		field := destType.Field(i)
+		destPath := joinPath(destPrefix, field.Name)
+		sourceFieldName, skip := mapOptions{}.mappedName(field)
+		if skip {
+			plan = append(plan, FieldMapping{DestPath: destPath, Skipped: true})
+			continue
+		}
+		if field.Anonymous && (mapOptions{}).promotesFields(field) {
+			plan = append(plan, resolveFields(sourceType, derefType(field.Type), sourcePrefix, destPath)...)
+			continue
+		}
+		sourcePath, sourceFieldType, ok := lookupSourceField(sourceType, sourceFieldName)
+		switch {
+		case !ok && field.Type.Kind() == reflect.Struct:
+			plan = append(plan, resolveFields(sourceType, field.Type, sourcePrefix, destPath)...)
+		case !ok:
+			plan = append(plan, FieldMapping{DestPath: destPath})
+		case isNestedStructPair(sourceFieldType, field.Type):
+			plan = append(plan, resolveFields(derefType(sourceFieldType), derefType(field.Type), joinPath(sourcePrefix, sourcePath), destPath)...)
+		default:
+			plan = append(plan, FieldMapping{SourcePath: joinPath(sourcePrefix, sourcePath), DestPath: destPath})
+		}
+	}
+	return plan
+}
+
+// lookupSourceField finds the source field providing the value for the
+// destination field that maps to name. Like mapByFieldName, it first looks
+// for a (possibly promoted) field of sourceType, and then for a field of the
+// same name in any of its struct fields.
+func lookupSourceField(sourceType reflect.Type, name string) (path string, fieldType reflect.Type, ok bool) {
+	if strings.Contains(name, "|") {
+		// Without values, the first candidate that exists is used.
+		for _, candidate := range strings.Split(name, "|") {
+			if path, fieldType, ok := lookupSourceField(sourceType, candidate); ok {
+				return path, fieldType, true
+			}
+		}
+		return "", nil, false
+	}
+	if path, fieldType, ok := lookupFieldType(sourceType, name); ok {
+		return path, fieldType, true
+	}
+	for i := 0; i < sourceType.NumField(); i++ {
+		field := sourceType.Field(i)
+		if field.Type.Kind() != reflect.Struct {
+			continue
+		}
+		if path, fieldType, ok := lookupFieldType(field.Type, name); ok {
+			return joinPath(field.Name, path), fieldType, true
+		}
+	}
+	return "", nil, false
+}
+
+// lookupFieldType is the type level equivalent of lookupField.
+func lookupFieldType(t reflect.Type, name string) (path string, fieldType reflect.Type, ok bool) {
+	fieldType = t
+	for _, step := range parsePath(name) {
+		fieldType = derefType(fieldType)
+		if fieldType.Kind() != reflect.Struct {
+			return "", nil, false
+		}
+		segment, ok := step.fieldName(fieldType)
+		if !ok {
+			return "", nil, false
+		}
+		structField, _ := fieldType.FieldByName(segment)
+		path = fieldPath(path, fieldType, segment)
+		fieldType = structField.Type
+	}
+	return path, fieldType, true
+}
+
+// isNestedStructPair returns true if both types are (pointers to) structs of
+// different types, which are mapped field by field.
+func isNestedStructPair(sourceType, destType reflect.Type) bool {
+	sourceType, destType = derefType(sourceType), derefType(destType)
+	return sourceType != destType && sourceType.Kind() == reflect.Struct && destType.Kind() == reflect.Struct
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolvePlan(t *testing.T) {
+	type source struct {
+		Foo   string
+		Name  string
+		Child SourceTypeA
+		Audit struct{ CreatedBy string }
+	}
+	type dest struct {
+		Foo       string
+		Bar       string `automapper:"Name"`
+		Baz       string `automapper:"-"`
+		Child     *DestTypeA
+		CreatedBy string
+		Missing   int
+	}
+
+	plan := ResolvePlan(reflect.TypeOf(source{}), reflect.TypeOf(&dest{}))
+	assert.Equal(t, []FieldMapping{
+		{SourcePath: "Foo", DestPath: "Foo"},
+		{SourcePath: "Name", DestPath: "Bar"},
+		{DestPath: "Baz", Skipped: true},
+		{SourcePath: "Child.Foo", DestPath: "Child.Foo"},
+		{SourcePath: "Child.Bar", DestPath: "Child.Bar"},
+		{SourcePath: "Audit.CreatedBy", DestPath: "CreatedBy"},
+		{DestPath: "Missing"},
+	}, plan)
+}
+
+func TestResolvePlanWithEmbeddedFields(t *testing.T) {
+	source := struct {
+		SourceTypeA
+	}{}
+	dest := struct {
+		DestTypeA
+	}{}
+
+	plan := ResolvePlan(reflect.TypeOf(source), reflect.TypeOf(dest))
+	assert.Equal(t, []FieldMapping{
+		{SourcePath: "SourceTypeA.Foo", DestPath: "DestTypeA.Foo"},
+		{SourcePath: "SourceTypeA.Bar", DestPath: "DestTypeA.Bar"},
+	}, plan)
+}
+
+func TestResolvePlanWithCandidateSourceFields(t *testing.T) {
+	source := struct{ DisplayName, Name string }{}
+	dest := struct {
+		Name string `automapper:"FullName|DisplayName|Name"`
+	}{}
+
+	plan := ResolvePlan(reflect.TypeOf(source), reflect.TypeOf(dest))
+	assert.Equal(t, []FieldMapping{{SourcePath: "DisplayName", DestPath: "Name"}}, plan)
+}
+
+func TestResolvePlanRequiresStructs(t *testing.T) {
+	assert.Nil(t, ResolvePlan(reflect.TypeOf(""), reflect.TypeOf(DestTypeA{})))
+}
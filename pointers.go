@@ -0,0 +1,33 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import "reflect"
+
+// pointerKey identifies a source pointer mapped to a destination pointer
+// type. The source type is part of the key, as a pointer to a struct and a
+// pointer to its first field have the same address.
+type pointerKey struct {
+	pointer              uintptr
+	sourceType, destType reflect.Type
+}
+
+// sharedPointer returns the destination pointer of type destType that the
+// source pointer sourceVal was mapped to before, if any.
+func (s *mapState) sharedPointer(sourceVal reflect.Value, destType reflect.Type) (reflect.Value, bool) {
+	if s == nil || s.pointers == nil || sourceVal.Kind() != reflect.Ptr {
+		return reflect.Value{}, false
+	}
+	shared, ok := s.pointers[pointerKey{sourceVal.Pointer(), sourceVal.Type(), destType}]
+	return shared, ok
+}
+
+// sharePointer records that the source pointer sourceVal is mapped to the
+// destination pointer destVal. It is recorded before the value it points to is
+// mapped, so that cycles back to sourceVal map to destVal as well.
+func (s *mapState) sharePointer(sourceVal, destVal reflect.Value) {
+	if s == nil || s.pointers == nil || sourceVal.Kind() != reflect.Ptr {
+		return
+	}
+	s.pointers[pointerKey{sourceVal.Pointer(), sourceVal.Type(), destVal.Type()}] = destVal
+}
@@ -0,0 +1,193 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type graphNode struct {
+	Name     string
+	Children []*graphNode
+}
+
+type graphNodeDTO struct {
+	Name     string
+	Children []*graphNodeDTO
+}
+
+func TestWithSharedPointersPreservesIdentity(t *testing.T) {
+	shared := &graphNode{Name: "shared"}
+	source := graphNode{Name: "root", Children: []*graphNode{
+		{Name: "a", Children: []*graphNode{shared}},
+		{Name: "b", Children: []*graphNode{shared}},
+	}}
+	dest := graphNodeDTO{}
+
+	MapToDestination(&source, &dest, WithSharedPointers())
+	assert.Equal(t, "shared", dest.Children[0].Children[0].Name)
+	assert.True(t, dest.Children[0].Children[0] == dest.Children[1].Children[0])
+}
+
+func TestPointersAreNotSharedByDefault(t *testing.T) {
+	shared := &graphNode{Name: "shared"}
+	source := graphNode{Children: []*graphNode{shared, shared}}
+	dest := graphNodeDTO{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, dest.Children[0], dest.Children[1])
+	assert.False(t, dest.Children[0] == dest.Children[1])
+}
+
+func TestWithSharedPointersMapsCycles(t *testing.T) {
+	root := &graphNode{Name: "root"}
+	root.Children = []*graphNode{{Name: "child", Children: []*graphNode{root}}}
+	dest := struct{ Root *graphNodeDTO }{}
+
+	MapToDestination(&struct{ Root *graphNode }{root}, &dest, WithSharedPointers())
+	assert.Equal(t, "child", dest.Root.Children[0].Name)
+	assert.True(t, dest.Root == dest.Root.Children[0].Children[0])
+}
+
+func TestPointersOfIdenticalTypesAreCloned(t *testing.T) {
+	type Address struct {
+		Street string
+		Tags   []string
+	}
+	source := struct {
+		Address *Address
+	}{&Address{Street: "Main", Tags: []string{"home"}}}
+	dest := struct {
+		Address *Address `automapper:"Address"`
+	}{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, source.Address, dest.Address)
+	dest.Address.Street = "Other"
+	dest.Address.Tags[0] = "work"
+	assert.Equal(t, "Main", source.Address.Street)
+	assert.Equal(t, "home", source.Address.Tags[0])
+}
+
+func TestPointersOfIdenticalTypesAreClonedWithCycles(t *testing.T) {
+	type Node struct {
+		Name string
+		Next *Node
+	}
+	node := &Node{Name: "a"}
+	node.Next = node
+	source := struct{ Node *Node }{node}
+	dest := struct {
+		Node *Node `automapper:"Node"`
+	}{}
+
+	MapToDestination(&source, &dest)
+	assert.NotSame(t, node, dest.Node)
+	assert.Same(t, dest.Node, dest.Node.Next)
+}
+
+func TestPointersInValuesOfIdenticalTypesAreCloned(t *testing.T) {
+	type Holder struct {
+		Address *SourceTypeA
+	}
+	source := Holder{&SourceTypeA{Foo: 1}}
+	dest := Holder{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, source, dest)
+	dest.Address.Foo = 2
+	assert.Equal(t, 1, source.Address.Foo)
+
+	type Holders []Holder
+	sourceSlice := struct{ Holders []Holder }{[]Holder{source}}
+	destSlice := struct{ Holders Holders }{}
+
+	MapToDestination(&sourceSlice, &destSlice)
+	assert.NotSame(t, source.Address, destSlice.Holders[0].Address)
+}
+
+func TestPointersToValuesWithUnexportedFieldsAreKept(t *testing.T) {
+	type Account struct {
+		Amount *big.Int
+		Lock   *sync.Mutex
+	}
+	source := Account{big.NewInt(5), &sync.Mutex{}}
+	source.Lock.Lock()
+	dest := Account{}
+
+	MapToDestination(&source, &dest)
+	assert.Same(t, source.Amount, dest.Amount)
+	assert.Same(t, source.Lock, dest.Lock)
+
+	copyInt := func(_ context.Context, v interface{}) (interface{}, error) {
+		return new(big.Int).Set(v.(*big.Int)), nil
+	}
+	dest = Account{}
+	MapToDestination(&source, &dest, WithConverter(reflect.TypeOf(source.Amount), reflect.TypeOf(source.Amount), copyInt))
+	assert.NotSame(t, source.Amount, dest.Amount)
+	dest.Amount.SetUint64(7)
+	assert.Equal(t, int64(5), source.Amount.Int64())
+}
+
+func TestCopiedValuesOfIdenticalTypesHonorMaxDepthAndContext(t *testing.T) {
+	source := struct{ Head *nestedNode }{newNestedNodes(100)}
+	dest := struct{ Head *nestedNode }{}
+
+	err := NewMapper(WithMaxDepth(5)).MapDir(&source, &dest, ToDestination)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "maximum mapping depth of 5 exceeded")
+	assert.NoError(t, NewMapper().MapDir(&source, &dest, ToDestination))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.True(t, errors.Is(MapToDestinationContext(ctx, &source, &dest), context.Canceled))
+}
+
+func TestWithReuseNestedPointers(t *testing.T) {
+	source := struct {
+		Child  *SourceTypeA
+		Other  *SourceTypeA
+		Nil    *SourceTypeA
+		Same   *DestTypeA
+		Values []*SourceTypeA
+	}{
+		Child:  &SourceTypeA{Foo: 1, Bar: "child"},
+		Other:  &SourceTypeA{Foo: 2},
+		Same:   &DestTypeA{Foo: 3},
+		Values: []*SourceTypeA{{Foo: 4}},
+	}
+	pooled, same := &DestTypeA{Foo: 42}, &DestTypeA{}
+	dest := struct {
+		Child  *DestTypeA
+		Other  *DestTypeA
+		Nil    *DestTypeA
+		Same   *DestTypeA
+		Values []*DestTypeA
+	}{Child: pooled, Nil: &DestTypeA{}, Same: same}
+
+	MapToDestination(&source, &dest, WithReuseNestedPointers())
+	assert.Same(t, pooled, dest.Child)
+	assert.Equal(t, DestTypeA{Foo: 1, Bar: "child"}, *dest.Child)
+	assert.Equal(t, &DestTypeA{Foo: 2}, dest.Other)
+	assert.Nil(t, dest.Nil)
+	assert.Same(t, same, dest.Same)
+	assert.Equal(t, DestTypeA{Foo: 3}, *dest.Same)
+	assert.Equal(t, []*DestTypeA{{Foo: 4}}, dest.Values)
+}
+
+func TestPointersAreReplacedByDefault(t *testing.T) {
+	source := struct{ Child *SourceTypeA }{&SourceTypeA{Foo: 1}}
+	pooled := &DestTypeA{}
+	dest := struct{ Child *DestTypeA }{pooled}
+
+	MapToDestination(&source, &dest)
+	assert.NotSame(t, pooled, dest.Child)
+	assert.Equal(t, DestTypeA{}, *pooled)
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import "fmt"
+
+// SkipReason tells why a field was left out of a mapping.
+type SkipReason int
+
+const (
+	// SkipTagged is the reason for fields tagged `automapper:"-"`.
+	SkipTagged SkipReason = iota
+	// SkipNilEmbedded is the reason for source fields that are promoted
+	// through a nil embedded pointer or interface, so they have no value.
+	SkipNilEmbedded
+	// SkipEmpty is the reason for fields tagged omitempty whose source value
+	// is empty.
+	SkipEmpty
+	// SkipMissingSource is the reason for destination fields without a
+	// source field, which received their default value instead, or were left
+	// unchanged because the destination embeds the source type.
+	SkipMissingSource
+)
+
+func (r SkipReason) String() string {
+	switch r {
+	case SkipTagged:
+		return "tagged \"-\""
+	case SkipNilEmbedded:
+		return "promoted through a nil embedded pointer or interface"
+	case SkipEmpty:
+		return "empty and tagged omitempty"
+	case SkipMissingSource:
+		return "no source field"
+	}
+	return "unknown"
+}
+
+// SkippedField describes a field that was left out of a mapping.
+type SkippedField struct {
+	// Path is the dotted path of the destination field. For source fields
+	// skipped while mapping FromSource, it is the path of the source field.
+	Path   string
+	Reason SkipReason
+}
+
+// MapReport lists the fields that were left out of a mapping, which helps to
+// find data lost to misconfigured tags or types.
+type MapReport struct {
+	Skipped []SkippedField
+}
+
+// MapWithReport works like MapDir, but also returns a report of the fields
+// that were skipped. The report is returned even if the mapping fails, and
+// then lists the fields skipped before the failure.
+func (m *Mapper) MapWithReport(source, dest interface{}, dir Direction) (report *MapReport, err error) {
+	report = &MapReport{}
+	defer recoverError(&err)
+	opts := newMapOptions(dir == FromSource, m.opts)
+	opts.report = report
+	mapTopLevel(source, dest, opts)
+	return report, nil
+}
+
+// recordsSkips returns true if skipped fields are logged or reported. Callers
+// check it before building the path of a skipped field, so skipping fields
+// costs nothing otherwise.
+func (o mapOptions) recordsSkips() bool {
+	return o.logger != nil || o.report != nil
+}
+
+// skipField logs the message given by format and args for the field at path,
+// and adds the field to the report of the mapping, if any.
+func (o mapOptions) skipField(path string, reason SkipReason, format string, args ...interface{}) {
+	if o.logger != nil {
+		o.logger(path, fmt.Sprintf(format, args...))
+	}
+	if o.report != nil {
+		o.report.Skipped = append(o.report.Skipped, SkippedField{Path: path, Reason: reason})
+	}
+}
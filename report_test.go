@@ -0,0 +1,76 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapWithReportListsSkippedFields(t *testing.T) {
+	type Inner struct {
+		Bar string
+	}
+	type Source struct {
+		*Inner
+		Foo   string
+		Empty string
+	}
+	type Dest struct {
+		Foo     string
+		Secret  string `automapper:"-"`
+		Bar     string
+		Empty   string `automapper:",omitempty"`
+		Country string `automapper:",default=NL"`
+	}
+	dest := Dest{}
+
+	report, err := NewMapper().MapWithReport(Source{Foo: "foo"}, &dest, ToDestination)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", dest.Foo)
+	assert.Equal(t, []SkippedField{
+		{Path: "Secret", Reason: SkipTagged},
+		{Path: "Bar", Reason: SkipNilEmbedded},
+		{Path: "Empty", Reason: SkipEmpty},
+		{Path: "Country", Reason: SkipMissingSource},
+	}, report.Skipped)
+}
+
+func TestMapWithReportFromSource(t *testing.T) {
+	type Inner struct {
+		Bar string
+	}
+	type Source struct {
+		*Inner
+		Foo    string
+		Secret string `automapper:"-"`
+	}
+	type Dest struct {
+		Foo, Bar string
+	}
+	dest := Dest{}
+
+	report, err := NewMapper().MapWithReport(Source{Foo: "foo"}, &dest, FromSource)
+	assert.NoError(t, err)
+	assert.Equal(t, []SkippedField{
+		{Path: "Inner", Reason: SkipNilEmbedded},
+		{Path: "Secret", Reason: SkipTagged},
+	}, report.Skipped)
+}
+
+func TestMapWithReportReturnsReportOnError(t *testing.T) {
+	source := struct{ Foo string }{}
+	dest := struct {
+		Foo string `automapper:"-"`
+		Bar string
+	}{}
+
+	report, err := NewMapper().MapWithReport(source, &dest, ToDestination)
+	assert.Error(t, err)
+	assert.Equal(t, []SkippedField{{Path: "Foo", Reason: SkipTagged}}, report.Skipped)
+}
+
+func TestSkipReasonString(t *testing.T) {
+	assert.Equal(t, "empty and tagged omitempty", SkipEmpty.String())
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	valuerType  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+)
+
+// isScannerPair returns true if destType, or a pointer to it, implements
+// sql.Scanner, and values of sourceType, or the values they point to, are
+// driver values, i.e. booleans, numbers, strings, byte slices or times.
+func isScannerPair(sourceType, destType reflect.Type) bool {
+	if sourceType == destType || !isDriverValueType(derefType(sourceType)) {
+		return false
+	}
+	return isScannerType(destType)
+}
+
+// isScannerType returns true if t is a pointer implementing sql.Scanner, or
+// if a pointer to t implements it.
+func isScannerType(t reflect.Type) bool {
+	return t.Kind() == reflect.Ptr && t.Implements(scannerType) || reflect.PtrTo(t).Implements(scannerType)
+}
+
+func isDriverValueType(t reflect.Type) bool {
+	return t.Kind() == reflect.Bool || t.Kind() == reflect.String || isNumericKind(t.Kind()) ||
+		isByteSlice(t) || t == reflect.TypeOf(time.Time{})
+}
+
+// mapScanned passes sourceVal to the Scan method of destVal. A nil pointer
+// scans as nil, so e.g. a sql.NullString becomes invalid. Errors cause a
+// panic.
+func mapScanned(sourceVal, destVal reflect.Value) {
+	value, err := driver.DefaultParameterConverter.ConvertValue(sourceVal.Interface())
+	if err != nil {
+		panic(fmt.Errorf("cannot scan %v into %v: %w", sourceVal.Type(), destVal.Type(), err))
+	}
+	// Pointer destinations receive a new value, others are scanned in place
+	// of a copy, so a failing Scan leaves them unchanged.
+	byPointer := destVal.Kind() == reflect.Ptr && destVal.Type().Implements(scannerType)
+	var target reflect.Value
+	if byPointer {
+		target = reflect.New(destVal.Type().Elem())
+	} else {
+		target = reflect.New(destVal.Type())
+		target.Elem().Set(destVal)
+	}
+	if err := target.Interface().(sql.Scanner).Scan(value); err != nil {
+		panic(fmt.Errorf("cannot scan %v into %v: %w", sourceVal.Type(), destVal.Type(), err))
+	}
+	if byPointer {
+		destVal.Set(target)
+	} else {
+		destVal.Set(target.Elem())
+	}
+}
+
+// isValuerPair returns true if sourceType implements driver.Valuer, and values
+// of destType, or the values they point to, are driver values, or destType is
+// a scanner. Interfaces are excluded, as they are mapped through the values
+// they hold.
+func isValuerPair(sourceType, destType reflect.Type) bool {
+	return sourceType != destType && sourceType.Kind() != reflect.Interface && sourceType.Implements(valuerType) &&
+		(isDriverValueType(derefType(destType)) || isScannerType(destType))
+}
+
+// mapValued maps the result of the Value method of sourceVal into destVal, so
+// e.g. an int64 is converted to an int. A nil pointer or a nil value, like the
+// value of an invalid sql.NullString, maps to the zero value. Errors cause a
+// panic.
+func mapValued(sourceVal, destVal reflect.Value, opts mapOptions) {
+	if valueIsNil(sourceVal) {
+		destVal.Set(reflect.Zero(destVal.Type()))
+		return
+	}
+	value, err := sourceVal.Interface().(driver.Valuer).Value()
+	if err != nil {
+		panic(fmt.Errorf("cannot get the value of %v: %w", sourceVal.Type(), err))
+	}
+	if value == nil {
+		destVal.Set(reflect.Zero(destVal.Type()))
+		return
+	}
+	mapValues(reflect.ValueOf(value), destVal, opts)
+}
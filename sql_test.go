@@ -0,0 +1,151 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type amount struct {
+	Cents int64
+}
+
+func (m *amount) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case int64:
+		m.Cents = v
+	case string:
+		var euros, cents int64
+		if _, err := fmt.Sscanf(v, "%d.%d", &euros, &cents); err != nil {
+			return err
+		}
+		m.Cents = euros*100 + cents
+	default:
+		return errors.New("unsupported amount value")
+	}
+	return nil
+}
+
+func (m amount) Value() (driver.Value, error) {
+	if m.Cents < 0 {
+		return nil, errors.New("negative amount")
+	}
+	return fmt.Sprintf("%d.%02d", m.Cents/100, m.Cents%100), nil
+}
+
+func TestWithSQLValueTypesScansIntoNullTypes(t *testing.T) {
+	name := "foo"
+	now := time.Now()
+	source := struct {
+		Name    *string
+		Comment *string
+		Age     int
+		Active  bool
+		Created time.Time
+	}{&name, nil, 42, true, now}
+	dest := struct {
+		Name    sql.NullString
+		Comment sql.NullString
+		Age     sql.NullInt64
+		Active  sql.NullBool
+		Created sql.NullTime
+	}{}
+
+	MapToDestination(&source, &dest, WithSQLValueTypes())
+	assert.Equal(t, sql.NullString{String: "foo", Valid: true}, dest.Name)
+	assert.Equal(t, sql.NullString{}, dest.Comment)
+	assert.Equal(t, sql.NullInt64{Int64: 42, Valid: true}, dest.Age)
+	assert.Equal(t, sql.NullBool{Bool: true, Valid: true}, dest.Active)
+	assert.Equal(t, sql.NullTime{Time: now, Valid: true}, dest.Created)
+}
+
+func TestWithSQLValueTypesScansIntoCustomTypes(t *testing.T) {
+	source := struct {
+		Price    string
+		Discount int
+	}{"12.34", 50}
+	dest := struct {
+		Price    amount
+		Discount *amount
+	}{}
+
+	MapToDestination(&source, &dest, WithSQLValueTypes())
+	assert.Equal(t, amount{Cents: 1234}, dest.Price)
+	assert.Equal(t, &amount{Cents: 50}, dest.Discount)
+}
+
+func TestWithSQLValueTypesReturnsScanErrors(t *testing.T) {
+	source := struct{ Price string }{"free"}
+	dest := struct{ Price amount }{amount{Cents: 1}}
+
+	err := NewMapper(WithSQLValueTypes()).MapDir(&source, &dest, ToDestination)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot scan string into automapper.amount")
+	assert.Equal(t, amount{Cents: 1}, dest.Price)
+}
+
+func TestScannersAreNotUsedByDefault(t *testing.T) {
+	defer func() { recover() }()
+	source := struct{ Name string }{"foo"}
+	dest := struct{ Name sql.NullString }{}
+	MapToDestination(&source, &dest)
+	t.Error("Should have panicked")
+}
+
+func TestWithSQLValueTypesMapsValuersToScalars(t *testing.T) {
+	source := struct {
+		Name    sql.NullString
+		Comment sql.NullString
+		Age     sql.NullInt64
+		Price   amount
+		Total   *amount
+		Valuer  driver.Valuer
+	}{
+		Name:   sql.NullString{String: "foo", Valid: true},
+		Age:    sql.NullInt64{Int64: 42, Valid: true},
+		Price:  amount{Cents: 1234},
+		Valuer: sql.NullBool{Bool: true, Valid: true},
+	}
+	dest := struct {
+		Name    string
+		Comment *string
+		Age     int
+		Price   string
+		Total   string
+		Valuer  bool
+	}{Comment: new(string), Total: "total"}
+
+	MapToDestination(&source, &dest, WithSQLValueTypes())
+	assert.Equal(t, "foo", dest.Name)
+	assert.Nil(t, dest.Comment)
+	assert.Equal(t, 42, dest.Age)
+	assert.Equal(t, "12.34", dest.Price)
+	assert.Equal(t, "", dest.Total)
+	assert.True(t, dest.Valuer)
+}
+
+func TestWithSQLValueTypesRoundTrips(t *testing.T) {
+	source := struct{ Price amount }{amount{Cents: 1234}}
+	row := struct{ Price sql.NullString }{}
+	dest := struct{ Price amount }{}
+
+	MapToDestination(&source, &row, WithSQLValueTypes())
+	MapToDestination(&row, &dest, WithSQLValueTypes())
+	assert.Equal(t, source, dest)
+}
+
+func TestWithSQLValueTypesReturnsValueErrors(t *testing.T) {
+	source := struct{ Price amount }{amount{Cents: -1}}
+	dest := struct{ Price string }{}
+
+	err := NewMapper(WithSQLValueTypes()).MapDir(&source, &dest, ToDestination)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot get the value of automapper.amount: negative amount")
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// mapState holds state shared by all the recursive calls of a single mapping.
+// A nil *mapState is valid and records nothing. The visited and completed
+// paths are only recorded for mappings verifying their fields, and pointers
+// only when shared pointers are preserved.
+type mapState struct {
+	sourceVisited, sourceCompleted map[string]bool
+	destVisited, destCompleted     map[string]bool
+	pointers                       map[pointerKey]reflect.Value
+}
+
+// newMapState returns the state needed by opts, or nil if opts don't need
+// any.
+func newMapState(opts mapOptions) *mapState {
+	if !opts.verifiesFields() && !opts.sharedPointers {
+		return nil
+	}
+	s := &mapState{}
+	if opts.verifiesFields() {
+		s.sourceVisited = map[string]bool{}
+		s.sourceCompleted = map[string]bool{}
+		s.destVisited = map[string]bool{}
+		s.destCompleted = map[string]bool{}
+	}
+	if opts.sharedPointers {
+		s.pointers = map[pointerKey]reflect.Value{}
+	}
+	return s
+}
+
+// verifiesFields returns true if the fields that took part in the mapping
+// are verified afterwards.
+func (o mapOptions) verifiesFields() bool {
+	return o.strict || o.noExtraSourceFields && !o.useSourceMemberList
+}
+
+// visit records that the values at the two paths took part in the mapping.
+// The parents of the paths are recorded as visited too.
+func (s *mapState) visit(sourcePath, destPath string) {
+	if s == nil || s.sourceVisited == nil {
+		return
+	}
+	visitPath(s.sourceVisited, sourcePath)
+	visitPath(s.destVisited, destPath)
+}
+
+// complete records that the values at the two paths were copied as a whole,
+// so all of their fields are mapped as well.
+func (s *mapState) complete(sourcePath, destPath string) {
+	if s == nil || s.sourceCompleted == nil {
+		return
+	}
+	s.sourceCompleted[sourcePath] = true
+	s.destCompleted[destPath] = true
+}
+
+func visitPath(visited map[string]bool, path string) {
+	for path != "" && !visited[path] {
+		visited[path] = true
+		if i := strings.LastIndex(path, "."); i >= 0 {
+			path = path[:i]
+		} else {
+			path = ""
+		}
+	}
+}
+
+// verifyAllFieldsMapped panics if the type driving the mapping has fields that
+// were not used, or the other type has fields that were not set.
+func verifyAllFieldsMapped(sourceType, destType reflect.Type, opts mapOptions) {
+	if opts.useSourceMemberList {
+		if unmapped := unvisitedFields(destType, "", opts.state.destVisited, opts.state.destCompleted, opts); len(unmapped) > 0 {
+			panic(fmt.Sprintf("destination fields were not mapped: [%s]", strings.Join(unmapped, ", ")))
+		}
+	} else {
+		if unused := unvisitedFields(sourceType, "", opts.state.sourceVisited, opts.state.sourceCompleted, opts); len(unused) > 0 {
+			panic(fmt.Sprintf("source fields were not used: [%s]", strings.Join(unused, ", ")))
+		}
+	}
+}
+
+// unvisitedFields returns the paths of the exported fields of t that were
+// not visited. Visited structs are searched recursively.
+func unvisitedFields(t reflect.Type, prefix string, visited, completed map[string]bool, opts mapOptions) []string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || completed[prefix] {
+		return nil
+	}
+	var result []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if _, skip := opts.mappedName(field); skip || field.PkgPath != "" {
+			continue
+		}
+		path := joinPath(prefix, field.Name)
+		if !visited[path] {
+			result = append(result, path)
+			continue
+		}
+		result = append(result, unvisitedFields(field.Type, path, visited, completed, opts)...)
+	}
+	return result
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithStrictPassesWhenInSync(t *testing.T) {
+	source := struct {
+		Foo   string
+		Child SourceTypeA
+	}{}
+	dest := struct {
+		Foo   string
+		Child DestTypeA
+	}{}
+	MapFromSource(&source, &dest, WithStrict())
+	MapToDestination(&source, &dest, WithStrict())
+}
+
+func TestWithStrictReportsUnmappedDestFields(t *testing.T) {
+	source := struct {
+		Foo   string
+		Child struct{ Foo int }
+	}{}
+	dest := struct {
+		Foo   string
+		Bar   string
+		Baz   string `automapper:"-"`
+		Child DestTypeA
+	}{}
+	err := NewMapper(WithStrict()).MapDir(&source, &dest, FromSource)
+	assert.EqualError(t, err, "destination fields were not mapped: [Bar, Child.Bar]")
+}
+
+func TestWithStrictReportsDestFieldsMissingFromSourceMap(t *testing.T) {
+	dest := struct {
+		Foo, Bar string
+	}{}
+	err := SafeMap(func() { MapFromSourceMap(map[string]interface{}{"Foo": "a"}, &dest, WithStrict()) })
+	assert.EqualError(t, err, "destination fields were not mapped: [Bar]")
+}
+
+func TestWithStrictReportsUnusedSourceFields(t *testing.T) {
+	source := struct {
+		Foo   string
+		Bar   string
+		Child SourceTypeA
+	}{}
+	dest := struct {
+		Foo   string
+		Child struct{ Foo int }
+	}{}
+	err := NewMapper(WithStrict()).MapDir(&source, &dest, ToDestination)
+	assert.EqualError(t, err, "source fields were not used: [Bar, Child.Bar]")
+}
+
+func TestWithStrictFollowsEmbeddedFields(t *testing.T) {
+	source := SourceTypeA{}
+	dest := struct {
+		DestTypeA
+	}{}
+	MapFromSource(&source, &dest, WithStrict())
+}
+
+func TestStrictWithPromotedFieldsFromTwoEmbeddingLevels(t *testing.T) {
+	source := struct {
+		Name string
+		Entity
+	}{"foo", Entity{ID: 1, Audit: Audit{CreatedAt: 2, UpdatedAt: 3}}}
+	dest := struct {
+		Name                     string
+		ID, CreatedAt, UpdatedAt int
+	}{}
+
+	MapToDestination(&source, &dest, WithStrict())
+	assert.Equal(t, 3, dest.UpdatedAt)
+}
+
+func TestWithNoExtraSourceFieldsReportsUnusedSourceFields(t *testing.T) {
+	source := struct {
+		Foo   string
+		Bar   string
+		Child SourceTypeA
+	}{}
+	dest := struct {
+		Foo   string
+		Baz   string `automapper:",default=baz"`
+		Child struct{ Foo int }
+	}{}
+	err := NewMapper(WithNoExtraSourceFields()).MapDir(&source, &dest, ToDestination)
+	assert.EqualError(t, err, "source fields were not used: [Bar, Child.Bar]")
+}
+
+func TestWithNoExtraSourceFieldsAcceptsRenamedAndSkippedFields(t *testing.T) {
+	source := struct {
+		ID       int
+		Password string
+		Internal string `automapper:"-"`
+		SourceTypeA
+	}{ID: 1, Password: "secret", SourceTypeA: SourceTypeA{Foo: 2, Bar: "bar"}}
+	dest := struct {
+		Key      int    `automapper:"ID"`
+		Password string `automapper:"-"`
+		Foo      int
+		Bar      string
+	}{}
+
+	MapToDestination(&source, &dest, WithNoExtraSourceFields())
+	assert.Equal(t, 1, dest.Key)
+	assert.Equal(t, "", dest.Password)
+}
+
+func TestWithNoExtraSourceFieldsIgnoresMappingFromSource(t *testing.T) {
+	source := struct{ Foo string }{"foo"}
+	dest := struct{ Foo, Bar string }{}
+
+	MapFromSource(&source, &dest, WithNoExtraSourceFields())
+	assert.Equal(t, "foo", dest.Foo)
+}
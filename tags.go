@@ -0,0 +1,266 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fieldTag holds the parsed automapper tag of a struct field. The tag has the
+// form `automapper:"Name,option1,option2"`, where the name may be left empty
+// to keep the name of the field itself, unless WithTagParser gives another
+// syntax.
+type fieldTag struct {
+	// name is the name of the field on the other side of the mapping. For a
+	// destination field, it may list several candidate source fields
+	// separated by "|", see candidateFieldName.
+	name string
+	// skip is true for fields tagged "-", which are not mapped.
+	skip bool
+	// omitEmpty skips the field when the source value is the zero value.
+	omitEmpty bool
+	// defaultValue is assigned when the source value is missing or zero, if
+	// hasDefault is set.
+	defaultValue string
+	hasDefault   bool
+	// timeUnit is time.Second or time.Millisecond for fields tagged "unix"
+	// or "unixmilli", which map between time.Time and integer timestamps.
+	timeUnit time.Duration
+	// converterName names the converter registered with WithNamedConverter
+	// that converts the value, for fields tagged "conv=name".
+	converterName string
+	// hasOptions is true if the tag has any options besides the name.
+	hasOptions bool
+}
+
+// TagParser interprets the struct tag of a field. It returns the name of the
+// field on the other side of the mapping, which may be empty to keep the name
+// of the field itself, the options of the tag, like "omitempty", and whether
+// the field is skipped. See WithTagParser.
+type TagParser func(tag reflect.StructTag) (name string, options []string, skip bool)
+
+// DefaultTagParser is the TagParser used unless WithTagParser is given. It
+// parses tags of the form `automapper:"Name,option1,option2"`, and skips
+// fields tagged `automapper:"-"`.
+func DefaultTagParser(tag reflect.StructTag) (name string, options []string, skip bool) {
+	automapperTag, ok := tag.Lookup("automapper")
+	if !ok {
+		return "", nil, false
+	}
+	if automapperTag == "-" {
+		return "", nil, true
+	}
+	parts := strings.Split(automapperTag, ",")
+	return parts[0], parts[1:], false
+}
+
+// parseTag parses the tag of field with DefaultTagParser. It is used where
+// results are cached per type, which is only done when there is no custom
+// tag parser.
+func parseTag(field reflect.StructField) fieldTag {
+	return mapOptions{}.parseTag(field)
+}
+
+// parseTag parses the tag of field with the tag parser of the options.
+func (o mapOptions) parseTag(field reflect.StructField) fieldTag {
+	parser := o.tagParser
+	if parser == nil {
+		parser = DefaultTagParser
+	}
+	name, options, skip := parser(field.Tag)
+	if skip {
+		return fieldTag{skip: true}
+	}
+	tag := fieldTag{name: name}
+	if tag.name == "" {
+		tag.name = field.Name
+	}
+	tag.hasOptions = len(options) > 0
+	for i, option := range options {
+		switch {
+		case option == "omitempty":
+			tag.omitEmpty = true
+		case option == "unix":
+			tag.timeUnit = time.Second
+		case option == "unixmilli":
+			tag.timeUnit = time.Millisecond
+		case strings.HasPrefix(option, "conv="):
+			tag.converterName = strings.TrimPrefix(option, "conv=")
+		case strings.HasPrefix(option, "default="):
+			// The default value is always the last option, so it may
+			// contain commas itself.
+			tag.defaultValue = strings.TrimPrefix(strings.Join(options[i:], ","), "default=")
+			tag.hasDefault = true
+			return tag
+		}
+	}
+	return tag
+}
+
+// mappedName returns the name of the field on the other side of the mapping
+// that field maps to. This is the name given in the automapper tag, or the
+// name of the field itself when there is no tag. skip is true for fields that
+// are tagged "-".
+func (o mapOptions) mappedName(field reflect.StructField) (name string, skip bool) {
+	tag := o.parseTag(field)
+	return tag.name, tag.skip
+}
+
+// promotesFields returns true if the fields of the embedded struct field are
+// mapped as if they were fields of the struct embedding it. A tag naming
+// another field maps the embedded struct as a whole to that field instead.
+func (o mapOptions) promotesFields(field reflect.StructField) bool {
+	return o.parseTag(field).name == field.Name
+}
+
+// candidateFieldName picks the source field to map from when name lists
+// candidates separated by "|", e.g. "FullName|DisplayName|Name". The first
+// candidate that exists in source and has a non-empty value wins. If all of
+// the candidates that exist are empty, the first of them is used, so its
+// value is still mapped. If none of them exist, name is returned unchanged.
+func (o mapOptions) candidateFieldName(source reflect.Value, name string) string {
+	if !strings.Contains(name, "|") {
+		return name
+	}
+	first := ""
+	for _, candidate := range strings.Split(name, "|") {
+		field, _, found := lookupField(source, candidate)
+		if !found {
+			continue
+		}
+		if field.IsValid() && !o.isZero(field) {
+			return candidate
+		}
+		if first == "" {
+			first = candidate
+		}
+	}
+	if first == "" {
+		return name
+	}
+	return first
+}
+
+// affixedFieldName returns the name of the field of struct type t that matches
+// name when prefixes or suffixes are added to or stripped from either name.
+// See WithStripPrefix.
+func (o mapOptions) affixedFieldName(t reflect.Type, name string) (string, bool) {
+	if t.Kind() != reflect.Struct || len(o.prefixes) == 0 && len(o.suffixes) == 0 || isPath(name) {
+		return "", false
+	}
+	var candidates []string
+	for _, prefix := range o.prefixes {
+		candidates = append(candidates, prefix+name)
+		if stripped := strings.TrimPrefix(name, prefix); stripped != name && stripped != "" {
+			candidates = append(candidates, stripped)
+		}
+	}
+	for _, suffix := range o.suffixes {
+		candidates = append(candidates, name+suffix)
+		if stripped := strings.TrimSuffix(name, suffix); stripped != name && stripped != "" {
+			candidates = append(candidates, stripped)
+		}
+	}
+	for _, candidate := range candidates {
+		if _, ok := t.FieldByName(candidate); ok {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// logicalNameIndexes caches the result of logicalNameIndex per type.
+var logicalNameIndexes sync.Map
+
+// fieldNameByMappedName returns the name of the field of struct type t that
+// maps to logicalName, either by its automapper tag or by its own name. If
+// there is no such field, logicalName is returned unchanged. ok is false if
+// logicalName is the name of a field that is tagged with another name, as a
+// tagged field is only matched by its tag.
+func (o mapOptions) fieldNameByMappedName(t reflect.Type, logicalName string) (fieldName string, ok bool) {
+	if fieldName, ok := o.logicalNameIndex(t)[logicalName]; ok {
+		return fieldName, true
+	}
+	if _, ok := t.FieldByName(logicalName); ok {
+		return logicalName, false
+	}
+	return logicalName, true
+}
+
+// logicalNameIndex returns a map from the logical names of the fields of t to
+// their field names. Fields promoted from embedded structs are included, but
+// never take precedence over the fields of t itself. The index is only cached
+// for the default tag parser.
+func (o mapOptions) logicalNameIndex(t reflect.Type) map[string]string {
+	cached := o.tagParser == nil
+	if index, ok := logicalNameIndexes.Load(t); ok && cached {
+		return index.(map[string]string)
+	}
+	index := map[string]string{}
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			if fieldType := derefType(field.Type); fieldType.Kind() == reflect.Struct {
+				embedded = append(embedded, fieldType)
+			}
+		}
+		if name, skip := o.mappedName(field); !skip {
+			index[name] = field.Name
+		}
+	}
+	for _, embeddedType := range embedded {
+		for name, fieldName := range o.logicalNameIndex(embeddedType) {
+			if _, ok := index[name]; !ok {
+				index[name] = fieldName
+			}
+		}
+	}
+	if cached {
+		logicalNameIndexes.Store(t, index)
+	}
+	return index
+}
+
+// setDefault parses the default value of a tag into the type of destVal and
+// assigns it. Strings, booleans, and numbers are supported, as well as
+// pointers to those.
+func setDefault(destVal reflect.Value, value string) {
+	if destVal.Kind() == reflect.Ptr {
+		val := reflect.New(destVal.Type().Elem())
+		setDefault(val.Elem(), value)
+		destVal.Set(val)
+		return
+	}
+	var err error
+	switch kind := destVal.Kind(); {
+	case kind == reflect.String:
+		destVal.SetString(value)
+	case kind == reflect.Bool:
+		var b bool
+		b, err = strconv.ParseBool(value)
+		destVal.SetBool(b)
+	case isIntKind(kind):
+		var i int64
+		i, err = strconv.ParseInt(value, 10, destVal.Type().Bits())
+		destVal.SetInt(i)
+	case isUintKind(kind):
+		var u uint64
+		u, err = strconv.ParseUint(value, 10, destVal.Type().Bits())
+		destVal.SetUint(u)
+	case isFloatKind(kind):
+		var f float64
+		f, err = strconv.ParseFloat(value, destVal.Type().Bits())
+		destVal.SetFloat(f)
+	default:
+		panic(fmt.Sprintf("default values are not supported for type %v", destVal.Type()))
+	}
+	if err != nil {
+		panic(fmt.Sprintf("invalid default value %q for type %v: %v", value, destVal.Type(), err))
+	}
+}
@@ -0,0 +1,444 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTagMatching(t *testing.T) {
+	source := struct {
+		Foo string `automapper:"user_name"`
+		Baz int
+	}{"abc", 42}
+	dest := struct {
+		Bar string `automapper:"user_name"`
+		Baz int
+	}{}
+
+	MapToDestination(&source, &dest, WithTagMatching())
+	assert.Equal(t, "abc", dest.Bar)
+	assert.Equal(t, 42, dest.Baz)
+
+	dest.Bar, dest.Baz = "", 0
+	MapFromSource(&source, &dest, WithTagMatching())
+	assert.Equal(t, "abc", dest.Bar)
+	assert.Equal(t, 42, dest.Baz)
+}
+
+func TestWithTagMatchingIgnoresNamesOfTaggedFields(t *testing.T) {
+	source := struct {
+		Bar string `automapper:"other"`
+	}{"abc"}
+	dest := struct {
+		Bar string
+	}{}
+
+	err := SafeMap(func() { MapToDestination(&source, &dest, WithTagMatching()) })
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no source field 'Bar'")
+	_, err = CompileMapper(reflect.TypeOf(source), reflect.TypeOf(dest), WithTagMatching())
+	assert.Error(t, err)
+	err = SafeMap(func() { MapFromSource(&dest, &source, WithTagMatching()) })
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no destination field 'Bar'")
+	assert.Empty(t, dest.Bar)
+}
+
+func TestWithTagMatchingThroughEmbeddedStruct(t *testing.T) {
+	type Inner struct {
+		Foo string `automapper:"user_name"`
+	}
+	source := struct {
+		Inner
+	}{Inner{"abc"}}
+	dest := struct {
+		Bar string `automapper:"user_name"`
+	}{}
+
+	MapToDestination(&source, &dest, WithTagMatching())
+	assert.Equal(t, "abc", dest.Bar)
+}
+
+func TestOmitEmptyTag(t *testing.T) {
+	source := struct {
+		Foo string
+		Bar string
+	}{}
+	dest := struct {
+		Foo string `automapper:",omitempty"`
+		Baz string `automapper:"Bar,omitempty"`
+	}{"foo", "baz"}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, "foo", dest.Foo)
+	assert.Equal(t, "baz", dest.Baz)
+
+	source.Foo, source.Bar = "abc", "def"
+	MapToDestination(&source, &dest)
+	assert.Equal(t, "abc", dest.Foo)
+	assert.Equal(t, "def", dest.Baz)
+}
+
+func TestOmitEmptyTagOnSource(t *testing.T) {
+	source := struct {
+		Foo int `automapper:",omitempty"`
+		Bar int
+	}{}
+	dest := struct {
+		Foo int
+		Bar int
+	}{42, 42}
+
+	MapFromSource(&source, &dest)
+	assert.Equal(t, 42, dest.Foo)
+	assert.Equal(t, 0, dest.Bar)
+}
+
+func TestDefaultTag(t *testing.T) {
+	source := struct {
+		Status string
+		Count  int
+	}{}
+	dest := struct {
+		Status  string   `automapper:"Status,default=active"`
+		Count   int      `automapper:",default=10"`
+		Enabled bool     `automapper:",default=true"`
+		Ratio   *float64 `automapper:",default=0.5"`
+		List    string   `automapper:",default=a,b"`
+	}{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, "active", dest.Status)
+	assert.Equal(t, 10, dest.Count)
+	assert.Equal(t, true, dest.Enabled)
+	assert.Equal(t, 0.5, *dest.Ratio)
+	assert.Equal(t, "a,b", dest.List)
+}
+
+func TestDefaultTagDoesNotOverrideValues(t *testing.T) {
+	source := struct {
+		Status string
+	}{"inactive"}
+	dest := struct {
+		Status string `automapper:",default=active"`
+	}{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, "inactive", dest.Status)
+}
+
+func TestDefaultTagOnSource(t *testing.T) {
+	source := struct {
+		Count int `automapper:"Total,default=10"`
+	}{}
+	dest := struct {
+		Total int64
+	}{}
+
+	MapFromSource(&source, &dest)
+	assert.Equal(t, int64(10), dest.Total)
+}
+
+func TestDefaultTagWithInvalidValue(t *testing.T) {
+	defer func() { recover() }()
+	source := struct{}{}
+	dest := struct {
+		Count int `automapper:",default=abc"`
+	}{}
+
+	MapToDestination(&source, &dest)
+	t.Error("Should have panicked")
+}
+
+func TestWithStripPrefix(t *testing.T) {
+	source := struct {
+		UserID   int
+		UserName string
+		Email    string
+	}{1, "foo", "foo@example.com"}
+	dest := struct {
+		ID    int
+		Name  string
+		Email string
+	}{}
+
+	MapToDestination(&source, &dest, WithStripPrefix("User"))
+	assert.Equal(t, 1, dest.ID)
+	assert.Equal(t, "foo", dest.Name)
+	assert.Equal(t, "foo@example.com", dest.Email)
+
+	back := struct {
+		UserID   int
+		UserName string
+		Email    string
+	}{}
+	MapFromSource(&dest, &back, WithStripPrefix("User"))
+	assert.Equal(t, source, back)
+}
+
+func TestWithStripSuffix(t *testing.T) {
+	source := struct {
+		ID     int
+		NameV2 string
+	}{1, "foo"}
+	dest := struct {
+		IDV2 int
+		Name string
+	}{}
+
+	MapToDestination(&source, &dest, WithStripSuffix("V2"))
+	assert.Equal(t, 1, dest.IDV2)
+	assert.Equal(t, "foo", dest.Name)
+}
+
+func TestStripPrefixPrefersExactMatch(t *testing.T) {
+	source := struct {
+		ID     int
+		UserID int
+	}{1, 2}
+	dest := struct{ ID int }{}
+
+	MapToDestination(&source, &dest, WithStripPrefix("User"))
+	assert.Equal(t, 1, dest.ID)
+}
+
+func TestConverterNameWithOtherTagOptions(t *testing.T) {
+	source := struct{ Amount, Tax int64 }{0, 5}
+	dest := struct {
+		Amount string `automapper:",conv=money,omitempty"`
+		Tax    string `automapper:",omitempty,conv=money"`
+	}{"old", "old"}
+
+	MapToDestination(&source, &dest, moneyConverter)
+	assert.Equal(t, "old", dest.Amount)
+	assert.Equal(t, "0.05", dest.Tax)
+}
+
+func TestRenamedFieldsApplyNestedRenames(t *testing.T) {
+	type SourceItem struct {
+		Title string
+		Qty   int
+	}
+	type DestItem struct {
+		Name     string `automapper:"Title"`
+		Quantity int    `automapper:"Qty"`
+	}
+	source := struct {
+		Items []SourceItem
+		Main  SourceItem
+		Extra *SourceItem
+	}{
+		Items: []SourceItem{{"a", 1}, {"b", 2}},
+		Main:  SourceItem{"c", 3},
+		Extra: &SourceItem{"d", 4},
+	}
+	dest := struct {
+		Lines   []DestItem `automapper:"Items"`
+		Primary DestItem   `automapper:"Main"`
+		Bonus   *DestItem  `automapper:"Extra"`
+	}{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, []DestItem{{"a", 1}, {"b", 2}}, dest.Lines)
+	assert.Equal(t, DestItem{"c", 3}, dest.Primary)
+	assert.Equal(t, &DestItem{"d", 4}, dest.Bonus)
+}
+
+func TestRenamedFieldsApplyNestedRenamesFromSource(t *testing.T) {
+	type SourceItem struct {
+		Title string `automapper:"Name"`
+	}
+	type DestItem struct {
+		Name string
+	}
+	source := struct {
+		Items []SourceItem `automapper:"Lines"`
+		Main  SourceItem   `automapper:"Primary"`
+	}{
+		Items: []SourceItem{{"a"}},
+		Main:  SourceItem{"b"},
+	}
+	dest := struct {
+		Lines   []DestItem
+		Primary DestItem
+	}{}
+
+	MapFromSource(&source, &dest)
+	assert.Equal(t, []DestItem{{"a"}}, dest.Lines)
+	assert.Equal(t, DestItem{"b"}, dest.Primary)
+}
+
+func TestTagWithCandidateSourceFields(t *testing.T) {
+	type Dest struct {
+		Name string `automapper:"FullName|DisplayName|Name"`
+	}
+	v1 := struct{ Name string }{"v1"}
+	v2 := struct{ DisplayName, Name string }{"v2", "old"}
+	v3 := struct{ FullName, DisplayName string }{"", "v3"}
+
+	for source, expected := range map[interface{}]string{v1: "v1", v2: "v2", v3: "v3"} {
+		dest := Dest{}
+		MapToDestination(source, &dest)
+		assert.Equal(t, expected, dest.Name)
+	}
+}
+
+func TestTagWithCandidateSourceFieldsMapsFirstExistingWhenAllEmpty(t *testing.T) {
+	source := struct {
+		DisplayName string
+		Name        string
+	}{}
+	dest := struct {
+		Name string `automapper:"FullName|DisplayName|Name"`
+	}{"old"}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, "", dest.Name)
+}
+
+func TestTagWithCandidateSourceFieldsPanicsWhenNoneExist(t *testing.T) {
+	defer func() {
+		assert.Contains(t, fmt.Sprint(recover()), "no source field 'FullName|Name'")
+	}()
+	source := struct{ Foo string }{}
+	dest := struct {
+		Name string `automapper:"FullName|Name"`
+	}{}
+	MapToDestination(&source, &dest)
+	t.Error("Should have panicked")
+}
+
+func TestSkipTaggedEmbeddedStruct(t *testing.T) {
+	source := struct {
+		SourceTypeA
+		Name string
+	}{SourceTypeA{Foo: 1, Bar: "bar"}, "name"}
+	dest := struct {
+		DestTypeA `automapper:"-"`
+		Name      string
+	}{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, DestTypeA{}, dest.DestTypeA)
+	assert.Equal(t, "name", dest.Name)
+}
+
+func TestSkipTaggedEmbeddedStructFromSource(t *testing.T) {
+	source := struct {
+		SourceTypeA `automapper:"-"`
+		Name        string
+	}{SourceTypeA{Foo: 1, Bar: "bar"}, "name"}
+	dest := struct {
+		Name string
+	}{}
+
+	MapFromSource(&source, &dest)
+	assert.Equal(t, "name", dest.Name)
+}
+
+func TestRenameTaggedEmbeddedStruct(t *testing.T) {
+	source := struct {
+		Billing  SourceTypeA
+		Shipping SourceTypeA
+	}{SourceTypeA{Foo: 1, Bar: "billing"}, SourceTypeA{Foo: 2, Bar: "shipping"}}
+	dest := struct {
+		*DestTypeA `automapper:"Billing"`
+	}{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, &DestTypeA{Foo: 1, Bar: "billing"}, dest.DestTypeA)
+}
+
+func TestRenameTaggedEmbeddedStructFromSource(t *testing.T) {
+	source := struct {
+		SourceTypeA `automapper:"Shipping"`
+	}{SourceTypeA{Foo: 2, Bar: "shipping"}}
+	dest := struct {
+		Shipping DestTypeA
+	}{}
+
+	MapFromSource(&source, &dest)
+	assert.Equal(t, DestTypeA{Foo: 2, Bar: "shipping"}, dest.Shipping)
+}
+
+// semicolonTagParser reads tags of the form `map:"name;option"`.
+func semicolonTagParser(tag reflect.StructTag) (string, []string, bool) {
+	value, ok := tag.Lookup("map")
+	if !ok {
+		return "", nil, false
+	}
+	if value == "ignore" {
+		return "", nil, true
+	}
+	parts := strings.Split(value, ";")
+	return parts[0], parts[1:], false
+}
+
+func TestWithTagParser(t *testing.T) {
+	source := struct {
+		Foo  string
+		Bar  int
+		Name string
+	}{"", 42, "name"}
+	dest := struct {
+		Foo   string `map:";default=foo"`
+		Baz   int    `map:"Bar"`
+		Title string `map:"Name"`
+		Qux   string `map:"ignore"`
+	}{}
+
+	MapToDestination(&source, &dest, WithTagParser(semicolonTagParser))
+	assert.Equal(t, "foo", dest.Foo)
+	assert.Equal(t, 42, dest.Baz)
+	assert.Equal(t, "name", dest.Title)
+	assert.Equal(t, "", dest.Qux)
+}
+
+func TestWithTagParserIgnoresAutomapperTags(t *testing.T) {
+	defer func() { recover() }()
+	source := struct{ Name string }{"name"}
+	dest := struct {
+		Title string `automapper:"Name"`
+	}{}
+	MapToDestination(&source, &dest, WithTagParser(semicolonTagParser))
+	t.Error("Should have panicked")
+}
+
+func TestWithTagParserFromSourceAndStrict(t *testing.T) {
+	source := struct {
+		Foo string `map:"Bar"`
+		Baz int    `map:"ignore"`
+	}{"foo", 42}
+	dest := struct{ Bar string }{}
+
+	MapFromSource(&source, &dest, WithTagParser(semicolonTagParser), WithStrict())
+	assert.Equal(t, "foo", dest.Bar)
+}
+
+func TestWithTagParserAndTagMatching(t *testing.T) {
+	source := struct {
+		Foo string `map:"user_name"`
+	}{"abc"}
+	dest := struct {
+		Bar string `map:"user_name"`
+	}{}
+
+	MapToDestination(&source, &dest, WithTagParser(semicolonTagParser), WithTagMatching())
+	assert.Equal(t, "abc", dest.Bar)
+}
+
+func TestDefaultTagParser(t *testing.T) {
+	name, options, skip := DefaultTagParser(`automapper:"Foo,omitempty,default=a,b"`)
+	assert.Equal(t, "Foo", name)
+	assert.Equal(t, []string{"omitempty", "default=a", "b"}, options)
+	assert.False(t, skip)
+
+	_, _, skip = DefaultTagParser(`automapper:"-"`)
+	assert.True(t, skip)
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// timeConverter returns a converter between strings and times, which uses the
+// layout and location of the options.
+func (o *mapOptions) timeConverter(sourceType, destType reflect.Type) (converter, bool) {
+	layout, location := o.timeLayout, o.timeLocation
+	if layout == "" {
+		layout = time.RFC3339Nano
+	}
+	if location == nil {
+		location = time.UTC
+	}
+	switch {
+	case sourceType == stringType && destType == timeType:
+		return func(_ context.Context, sourceVal reflect.Value) reflect.Value {
+			value := sourceVal.String()
+			if value == "" {
+				return reflect.Zero(timeType)
+			}
+			t, err := time.ParseInLocation(layout, value, location)
+			if err != nil {
+				panic(fmt.Errorf("invalid time %q: %w", value, err))
+			}
+			return reflect.ValueOf(t)
+		}, true
+	case sourceType == timeType && destType == stringType:
+		return func(_ context.Context, sourceVal reflect.Value) reflect.Value {
+			t := sourceVal.Interface().(time.Time)
+			if t.IsZero() {
+				return reflect.ValueOf("")
+			}
+			return reflect.ValueOf(t.In(location).Format(layout))
+		}, true
+	}
+	return nil, false
+}
+
+// mapUnixTime maps between a time.Time and an integer Unix timestamp counted in
+// the given unit, for fields tagged "unix" or "unixmilli". The zero time maps
+// to 0 and 0 maps to the zero time, so that unset timestamps stay unset. It
+// panics if a timestamp doesn't fit in the destination integer type.
+// Timestamps are converted to times in the location of the options, which is
+// UTC by default.
+func mapUnixTime(sourceVal, destVal reflect.Value, unit time.Duration, opts mapOptions) {
+	verifyUnixTimePair(sourceVal.Type(), destVal.Type())
+	switch {
+	case sourceVal.Type() == timeType:
+		t := sourceVal.Interface().(time.Time)
+		if t.IsZero() {
+			destVal.SetInt(0)
+			return
+		}
+		timestamp := t.Unix()
+		if unit == time.Millisecond {
+			timestamp = t.UnixMilli()
+		}
+		if destVal.OverflowInt(timestamp) {
+			panic(fmt.Sprintf("Unix timestamp %d of %v overflows %v", timestamp, t, destVal.Type()))
+		}
+		destVal.SetInt(timestamp)
+	default:
+		timestamp := sourceVal.Int()
+		if timestamp == 0 {
+			destVal.Set(reflect.Zero(timeType))
+			return
+		}
+		location := opts.timeLocation
+		if location == nil {
+			location = time.UTC
+		}
+		t := time.Unix(timestamp, 0)
+		if unit == time.Millisecond {
+			t = time.UnixMilli(timestamp)
+		}
+		destVal.Set(reflect.ValueOf(t.In(location)))
+	}
+}
+
+// verifyUnixTimePair panics unless one of the types is time.Time and the
+// other one an integer type, which are the types mapped as Unix timestamps.
+func verifyUnixTimePair(sourceType, destType reflect.Type) {
+	if !(sourceType == timeType && isIntKind(destType.Kind()) || isIntKind(sourceType.Kind()) && destType == timeType) {
+		panic(fmt.Sprintf("cannot map %v to %v as a Unix timestamp", sourceType, destType))
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapTimeToUnixSeconds(t *testing.T) {
+	source := struct{ CreatedAt time.Time }{time.Date(2020, 1, 2, 3, 4, 5, 6000000, time.UTC)}
+	dest := struct {
+		CreatedAt int64 `automapper:",unix"`
+	}{}
+	MapToDestination(&source, &dest)
+	assert.Equal(t, int64(1577934245), dest.CreatedAt)
+}
+
+func TestMapUnixMillisToTime(t *testing.T) {
+	source := struct {
+		CreatedAt int64 `automapper:"Created,unixmilli"`
+	}{1577934245006}
+	dest := struct{ Created time.Time }{}
+	MapFromSource(&source, &dest)
+	assert.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 6000000, time.UTC), dest.Created)
+}
+
+func TestMapZeroTimeToUnixTimestamp(t *testing.T) {
+	source := struct{ CreatedAt time.Time }{}
+	dest := struct {
+		CreatedAt int64 `automapper:",unixmilli"`
+	}{42}
+	MapToDestination(&source, &dest)
+	assert.Equal(t, int64(0), dest.CreatedAt)
+
+	back := struct{ CreatedAt time.Time }{time.Now()}
+	MapFromSource(&dest, &back)
+	assert.True(t, back.CreatedAt.IsZero())
+}
+
+func TestMapTimeOutsideUnixNanoRangeToUnixTimestamp(t *testing.T) {
+	source := struct{ CreatedAt, UpdatedAt time.Time }{
+		time.Date(2500, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(1500, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	dest := struct {
+		CreatedAt int64 `automapper:",unix"`
+		UpdatedAt int64 `automapper:",unixmilli"`
+	}{}
+	MapToDestination(&source, &dest)
+	assert.Equal(t, int64(16725225600), dest.CreatedAt)
+	assert.Equal(t, int64(-14831769600000), dest.UpdatedAt)
+}
+
+func TestMapUnixTimestampOverflowingDestinationPanics(t *testing.T) {
+	defer func() {
+		assert.Contains(t, fmt.Sprint(recover()), "overflows int32")
+	}()
+	source := struct{ CreatedAt time.Time }{time.Date(2500, 1, 1, 0, 0, 0, 0, time.UTC)}
+	dest := struct {
+		CreatedAt int32 `automapper:",unix"`
+	}{}
+	MapToDestination(&source, &dest)
+	t.Error("Should have panicked")
+}
+
+func TestMapUnixTimestampToUnsupportedTypePanics(t *testing.T) {
+	defer func() { recover() }()
+	source := struct{ CreatedAt string }{"2020-01-02"}
+	dest := struct {
+		CreatedAt int64 `automapper:",unix"`
+	}{}
+	MapToDestination(&source, &dest)
+	t.Error("Should have panicked")
+}
+
+func TestMapStringToTime(t *testing.T) {
+	source := struct{ CreatedAt, UpdatedAt string }{"2020-01-02T03:04:05.006+01:00", ""}
+	dest := struct{ CreatedAt, UpdatedAt time.Time }{}
+
+	MapToDestination(&source, &dest)
+	assert.True(t, time.Date(2020, 1, 2, 2, 4, 5, 6000000, time.UTC).Equal(dest.CreatedAt))
+	assert.True(t, dest.UpdatedAt.IsZero())
+}
+
+func TestMapTimeToString(t *testing.T) {
+	source := struct{ CreatedAt, UpdatedAt time.Time }{time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600)), time.Time{}}
+	dest := struct{ CreatedAt, UpdatedAt string }{}
+
+	MapToDestination(&source, &dest)
+	assert.Equal(t, "2020-01-02T02:04:05Z", dest.CreatedAt)
+	assert.Equal(t, "", dest.UpdatedAt)
+}
+
+func TestMapTimePointersToStringPointers(t *testing.T) {
+	deletedAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	source := struct {
+		DeletedAt, ArchivedAt *time.Time
+	}{DeletedAt: &deletedAt}
+	dest := struct {
+		DeletedAt, ArchivedAt *string
+	}{}
+
+	MapToDestination(&source, &dest)
+	if assert.NotNil(t, dest.DeletedAt) {
+		assert.Equal(t, "2020-01-02T03:04:05Z", *dest.DeletedAt)
+	}
+	assert.Nil(t, dest.ArchivedAt)
+}
+
+func TestMapStringPointersToTimePointers(t *testing.T) {
+	deletedAt := "2020-01-02T03:04:05Z"
+	source := struct {
+		DeletedAt, ArchivedAt *string
+	}{DeletedAt: &deletedAt}
+	dest := struct {
+		DeletedAt, ArchivedAt *time.Time
+	}{}
+
+	MapToDestination(&source, &dest, WithTimeLayout(time.RFC3339))
+	if assert.NotNil(t, dest.DeletedAt) {
+		assert.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), *dest.DeletedAt)
+	}
+	assert.Nil(t, dest.ArchivedAt)
+}
+
+func TestMapInvalidStringToTimePanics(t *testing.T) {
+	defer func() { recover() }()
+	source := struct{ CreatedAt string }{"yesterday"}
+	dest := struct{ CreatedAt time.Time }{}
+
+	MapToDestination(&source, &dest)
+	t.Error("Should have panicked")
+}
+
+func TestWithTimeLayoutAndLocation(t *testing.T) {
+	amsterdam := time.FixedZone("Amsterdam", 2*3600)
+	source := struct{ CreatedAt string }{"2020-06-01 12:00"}
+	dest := struct{ CreatedAt time.Time }{}
+	opts := []Option{WithTimeLayout("2006-01-02 15:04"), WithTimeLocation(amsterdam)}
+
+	MapToDestination(&source, &dest, opts...)
+	assert.True(t, time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC).Equal(dest.CreatedAt))
+	assert.Equal(t, amsterdam, dest.CreatedAt.Location())
+
+	dest.CreatedAt = dest.CreatedAt.UTC()
+	MapToDestination(&dest, &source, opts...)
+	assert.Equal(t, "2020-06-01 12:00", source.CreatedAt)
+}
+
+func TestMapUnixTimestampWithTimeLocation(t *testing.T) {
+	amsterdam := time.FixedZone("Amsterdam", 2*3600)
+	source := struct {
+		CreatedAt int64 `automapper:",unix"`
+	}{1577934245}
+	dest := struct{ CreatedAt time.Time }{}
+
+	MapFromSource(&source, &dest, WithTimeLocation(amsterdam))
+	assert.Equal(t, amsterdam, dest.CreatedAt.Location())
+	assert.Equal(t, int64(1577934245), dest.CreatedAt.Unix())
+}
@@ -0,0 +1,29 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// exposeUnexported returns a value of an unexported field that can be read
+// and set like the value of an exported field. Other values, and values that
+// are not addressable, are returned unchanged.
+func exposeUnexported(field reflect.Value) reflect.Value {
+	if !field.IsValid() || field.CanInterface() || !field.CanAddr() {
+		return field
+	}
+	return reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem()
+}
+
+// addressable returns an addressable copy of val, unless it is addressable
+// already, so that its unexported fields can be exposed.
+func addressable(val reflect.Value) reflect.Value {
+	if !val.IsValid() || val.CanAddr() {
+		return val
+	}
+	result := reflect.New(val.Type()).Elem()
+	result.Set(val)
+	return result
+}
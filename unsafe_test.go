@@ -0,0 +1,58 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type privateState struct {
+	Name    string
+	counter int
+	tags    []string
+	inner   *privateInner
+	privateInner
+}
+
+type privateInner struct {
+	secret string
+}
+
+type privateStateCopy struct {
+	Name    string
+	counter int64
+	tags    []string
+	inner   *privateInner
+	privateInner
+}
+
+func TestWithUnsafeUnexported(t *testing.T) {
+	source := privateState{"foo", 42, []string{"a"}, &privateInner{"inner"}, privateInner{"embedded"}}
+	dest := privateStateCopy{}
+
+	MapToDestination(source, &dest, WithUnsafeUnexported())
+	assert.Equal(t, "foo", dest.Name)
+	assert.Equal(t, int64(42), dest.counter)
+	assert.Equal(t, []string{"a"}, dest.tags)
+	assert.Equal(t, "inner", dest.inner.secret)
+	assert.Equal(t, "embedded", dest.secret)
+}
+
+func TestWithUnsafeUnexportedFromSource(t *testing.T) {
+	source := privateStateCopy{Name: "foo", counter: 42}
+	dest := privateState{}
+
+	MapFromSource(&source, &dest, WithUnsafeUnexported())
+	assert.Equal(t, 42, dest.counter)
+}
+
+func TestUnexportedFieldsPanicByDefault(t *testing.T) {
+	defer func() { recover() }()
+	source := privateState{counter: 42}
+	dest := privateStateCopy{}
+
+	MapToDestination(&source, &dest)
+	t.Error("Should have panicked")
+}
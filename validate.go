@@ -0,0 +1,55 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Validatable is implemented by destination types that can check themselves
+// after they have been mapped. See WithValidation.
+type Validatable interface {
+	Validate() error
+}
+
+var validatableType = reflect.TypeOf((*Validatable)(nil)).Elem()
+
+// validate calls Validate on the struct destVal after its fields have been
+// mapped, if validation is enabled and the struct or a pointer to it
+// implements Validatable. A validation error panics like any other mapping
+// error.
+func validate(destVal reflect.Value, opts mapOptions) {
+	if !opts.validate {
+		return
+	}
+	target := destVal
+	if target.CanAddr() {
+		target = target.Addr()
+	}
+	if !target.Type().Implements(validatableType) {
+		return
+	}
+	if err := target.Interface().(Validatable).Validate(); err != nil {
+		panic(fmt.Errorf("validation of %v failed: %w", destVal.Type(), err))
+	}
+}
+
+// verifyRequiredFields panics if any of the required fields of destVal is
+// empty. A field reached through a nil pointer is empty as well.
+func verifyRequiredFields(destVal reflect.Value, opts mapOptions) {
+	var empty []string
+	for _, path := range opts.requiredFields {
+		field, _, found := lookupField(reflect.Indirect(destVal), path)
+		if !found {
+			panic(fmt.Sprintf("no destination field '%s'", path))
+		}
+		if !field.IsValid() || opts.isZero(field) {
+			empty = append(empty, path)
+		}
+	}
+	if len(empty) > 0 {
+		panic(fmt.Sprintf("required fields are empty: [%s]", strings.Join(empty, ", ")))
+	}
+}
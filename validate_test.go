@@ -0,0 +1,158 @@
+// Copyright (c) 2015 Peter Strøiman, distributed under the MIT license
+
+package automapper
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errEmptyName = errors.New("name must not be empty")
+
+type validatedChild struct {
+	Name string
+}
+
+func (c *validatedChild) Validate() error {
+	if c.Name == "" {
+		return errEmptyName
+	}
+	return nil
+}
+
+type validatedParent struct {
+	Child validatedChild
+	Count int
+}
+
+func (p validatedParent) Validate() error {
+	if p.Count < 0 {
+		return errors.New("count must not be negative")
+	}
+	return nil
+}
+
+func TestWithValidation(t *testing.T) {
+	source := struct {
+		Child struct{ Name string }
+		Count int
+	}{struct{ Name string }{"foo"}, 1}
+	dest := validatedParent{}
+
+	err := NewMapper(WithValidation()).MapDir(&source, &dest, ToDestination)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", dest.Child.Name)
+}
+
+func TestWithValidationReturnsTopLevelError(t *testing.T) {
+	source := struct {
+		Child struct{ Name string }
+		Count int
+	}{struct{ Name string }{"foo"}, -1}
+	dest := validatedParent{}
+
+	err := NewMapper(WithValidation()).MapDir(&source, &dest, ToDestination)
+	assert.EqualError(t, err, "validation of automapper.validatedParent failed: count must not be negative")
+}
+
+func TestWithValidationValidatesNestedStructs(t *testing.T) {
+	source := struct {
+		Child struct{ Name string }
+		Count int
+	}{}
+	dest := validatedParent{}
+
+	err := NewMapper(WithValidation()).MapDir(&source, &dest, ToDestination)
+	assert.True(t, errors.Is(err, errEmptyName))
+	assert.Contains(t, err.Error(), "Child")
+}
+
+func TestWithValidationValidatesDestinationOfSourceType(t *testing.T) {
+	source := validatedParent{Child: validatedChild{Name: "foo"}, Count: -1}
+	dest := validatedParent{}
+
+	err := NewMapper(WithValidation()).MapDir(&source, &dest, ToDestination)
+	assert.EqualError(t, err, "validation of automapper.validatedParent failed: count must not be negative")
+}
+
+func TestWithValidationValidatesNestedFieldsOfSourceType(t *testing.T) {
+	type holder struct {
+		Parent validatedParent
+	}
+	source := struct {
+		Parent validatedParent
+	}{validatedParent{Child: validatedChild{Name: "foo"}, Count: -1}}
+	dest := holder{}
+
+	err := NewMapper(WithValidation()).MapDir(&source, &dest, ToDestination)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "count must not be negative")
+	}
+}
+
+func TestValidationIsOptIn(t *testing.T) {
+	source := struct {
+		Child struct{ Name string }
+		Count int
+	}{Count: -1}
+	dest := validatedParent{}
+
+	err := NewMapper().MapDir(&source, &dest, ToDestination)
+	assert.NoError(t, err)
+}
+
+func TestWithRequiredFields(t *testing.T) {
+	type Tenant struct {
+		ID int
+	}
+	type Source struct {
+		ID            int
+		Name, Country string
+		Tenant        *Tenant
+	}
+	type Dest struct {
+		ID      int
+		Name    string `automapper:",default=unnamed"`
+		Country string
+		Tenant  *Tenant
+	}
+	mapper := NewMapper(WithRequiredFields("ID", "Name", "Tenant.ID"))
+
+	dest := Dest{}
+	err := mapper.MapDir(Source{ID: 1, Tenant: &Tenant{ID: 2}}, &dest, ToDestination)
+	assert.NoError(t, err)
+	assert.Equal(t, "unnamed", dest.Name)
+
+	dest = Dest{}
+	err = mapper.MapDir(Source{}, &dest, ToDestination)
+	assert.EqualError(t, err, "required fields are empty: [ID, Tenant.ID]")
+}
+
+func TestWithRequiredFieldsForMapsAndConvert(t *testing.T) {
+	type Dest struct {
+		ID   int `json:"id"`
+		Name string
+	}
+	required := WithRequiredFields("ID")
+
+	err := SafeMap(func() { MapFromSourceMap(map[string]interface{}{"Name": "foo"}, &Dest{}, required) })
+	assert.EqualError(t, err, "required fields are empty: [ID]")
+	err = SafeMap(func() { MapFromSourceMapJSON(map[string]interface{}{"Name": "foo"}, &Dest{}, required) })
+	assert.EqualError(t, err, "required fields are empty: [ID]")
+	_, err = Convert(struct {
+		ID   int
+		Name string
+	}{Name: "foo"}, reflect.TypeOf(Dest{}), required)
+	assert.EqualError(t, err, "required fields are empty: [ID]")
+}
+
+func TestWithRequiredFieldsPanicsForUnknownFields(t *testing.T) {
+	defer func() {
+		assert.Equal(t, "no destination field 'Baz'", recover())
+	}()
+	MapToDestination(&SourceTypeA{}, &DestTypeA{}, WithRequiredFields("Baz"))
+	t.Error("Should have panicked")
+}